@@ -5,42 +5,65 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	gomysql "github.com/go-sql-driver/mysql"
 	"github.com/toutaio/toutago-datamapper/adapter"
 )
 
 // MySQLAdapter implements the adapter.Adapter interface for MySQL databases.
 type MySQLAdapter struct {
 	db         *sql.DB
-	dsn        string
-	maxConn    int
-	maxIdle    int
-	connMaxAge int
-}
-
-// Config keys for MySQL adapter configuration
-const (
-	ConfigHost     = "host"
-	ConfigPort     = "port"
-	ConfigUser     = "user"
-	ConfigPassword = "password"
-	ConfigDatabase = "database"
-	ConfigSSL      = "ssl"
-	ConfigMaxConn  = "max_connections"
-	ConfigMaxIdle  = "max_idle"
-	ConfigConnAge  = "conn_max_age_seconds"
-)
+	connector  *connector
+	avail      *availability
+	breaker    *breaker
+	stats      counters
+	slow       slowQueries
+	tracing    *tracing
+	unobserve  []func()
+	replicas   *replicaSet
+	config     Config
+	tlsNames   []string
+	generators map[string]IDGenerator
+}
 
-// NewMySQLAdapter creates a new MySQL adapter instance.
+// NewMySQLAdapter creates a new MySQL adapter instance with the default configuration.
 func NewMySQLAdapter() *MySQLAdapter {
+	snowflake, _ := NewSnowflakeGenerator(0)
 	return &MySQLAdapter{
-		maxConn:    10,
-		maxIdle:    5,
-		connMaxAge: 3600,
+		config: DefaultConfig(),
+		generators: map[string]IDGenerator{
+			GeneratorUUIDv7:    UUIDv7Generator{},
+			GeneratorULID:      ULIDGenerator{},
+			GeneratorSnowflake: snowflake,
+		},
+	}
+}
+
+// NewMySQLAdapterWithOptions creates a MySQL adapter from the default
+// configuration modified by opts. Values passed to Connect override it.
+func NewMySQLAdapterWithOptions(opts ...Option) (*MySQLAdapter, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	a := NewMySQLAdapter()
+	a.config = cfg
+	if cfg.SnowflakeNodeID != 0 {
+		snowflake, err := NewSnowflakeGenerator(cfg.SnowflakeNodeID)
+		if err != nil {
+			return nil, err
+		}
+		a.RegisterIDGenerator(GeneratorSnowflake, snowflake)
 	}
+	return a, nil
 }
 
 // Name returns the adapter type identifier.
@@ -50,132 +73,250 @@ func (a *MySQLAdapter) Name() string {
 
 // Connect establishes connection to MySQL database.
 func (a *MySQLAdapter) Connect(ctx context.Context, config map[string]interface{}) error {
-	// Extract connection parameters
-	host := getStringConfig(config, ConfigHost, "localhost")
-	port := getIntConfig(config, ConfigPort, 3306)
-	user := getStringConfig(config, ConfigUser, "root")
-	password := getStringConfig(config, ConfigPassword, "")
-	database := getStringConfig(config, ConfigDatabase, "")
-	ssl := getStringConfig(config, ConfigSSL, "false")
+	// Overlay source configuration on the adapter's configuration
+	cfg := a.config
+	if err := cfg.load(config); err != nil {
+		return err
+	}
+	a.config = cfg
+	a.breaker = newBreaker(cfg.Breaker)
 
-	// Optional connection pooling parameters
-	if maxConn, ok := config[ConfigMaxConn].(int); ok {
-		a.maxConn = maxConn
+	if _, ok := config[ConfigSnowflakeNode]; ok {
+		snowflake, err := NewSnowflakeGenerator(cfg.SnowflakeNodeID)
+		if err != nil {
+			return err
+		}
+		a.RegisterIDGenerator(GeneratorSnowflake, snowflake)
 	}
-	if maxIdle, ok := config[ConfigMaxIdle].(int); ok {
-		a.maxIdle = maxIdle
+
+	// Build driver configuration
+	driverCfg, err := cfg.driverConfig()
+	if err != nil {
+		return err
 	}
-	if connAge, ok := config[ConfigConnAge].(int); ok {
-		a.connMaxAge = connAge
+
+	a.tracing = newTracing(cfg.TracerProvider, driverCfg.DBName)
+
+	// Open the primary and replica pools
+	a.deregisterTLS()
+	a.unobservePools()
+	db, conn, err := a.openPool(cfg, driverCfg)
+	if err != nil {
+		a.deregisterTLS()
+		return err
 	}
 
-	// Build DSN
-	a.dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&tls=%s",
-		user, password, host, port, database, ssl)
+	// Fail over between candidate primaries, draining the pool on a switch
+	if addrs := cfg.hostAddrs(); len(addrs) > 0 && cfg.DSN == "" && cfg.Socket == "" {
+		conn.failover = &failover{
+			hosts:   addrs,
+			onEvent: cfg.OnFailover,
+			drain: func() {
+				db.SetMaxIdleConns(0)
+				db.SetMaxIdleConns(cfg.MaxIdle)
+			},
+		}
+	}
 
-	// Open database connection
-	db, err := sql.Open("mysql", a.dsn)
+	replicas, err := a.openReplicas(cfg, driverCfg)
 	if err != nil {
-		return fmt.Errorf("mysql: failed to open connection: %w", err)
+		_ = db.Close()
+		a.deregisterTLS()
+		return err
 	}
 
-	// Configure connection pool
-	db.SetMaxOpenConns(a.maxConn)
-	db.SetMaxIdleConns(a.maxIdle)
+	// In lazy mode, reach the database in the background instead
+	if cfg.LazyConnect {
+		avail := newAvailability(db.PingContext, cfg.OnStateChange, cfg.ReconnectMinBackoff, cfg.ReconnectMaxBackoff)
+		conn.onConnect = func(err error) {
+			if err != nil {
+				avail.failed(err)
+			} else {
+				avail.succeeded()
+			}
+		}
+		a.db = db
+		a.connector = conn
+		a.replicas = replicas
+		a.avail = avail
+		avail.start()
+		a.startLagMonitor(cfg)
+		a.observePools()
+		return nil
+	}
 
 	// Verify connection
 	if err := db.PingContext(ctx); err != nil {
 		_ = db.Close()
+		replicas.close()
+		a.deregisterTLS()
 		return fmt.Errorf("mysql: failed to ping database: %w", err)
 	}
+	if err := replicas.ping(ctx); err != nil {
+		_ = db.Close()
+		replicas.close()
+		a.deregisterTLS()
+		return err
+	}
 
 	a.db = db
+	a.connector = conn
+	a.replicas = replicas
+	a.startLagMonitor(cfg)
+	a.observePools()
 	return nil
 }
 
+// openPool opens a connection pool for driverCfg with the pool settings of
+// cfg, registering the custom TLS configuration for its address if needed.
+func (a *MySQLAdapter) openPool(cfg Config, driverCfg *gomysql.Config) (*sql.DB, *connector, error) {
+	// Register the custom TLS configuration under a name owned by this adapter
+	if cfg.TLS.enabled() {
+		// With several candidate primaries the driver derives the server
+		// name from each address
+		addr := driverCfg.Addr
+		if len(cfg.Hosts) > 0 {
+			addr = ""
+		}
+		name, err := registerTLS(cfg.TLS, addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		a.tlsNames = append(a.tlsNames, name)
+		driverCfg.TLSConfig = name
+		driverCfg.TLS = nil
+	}
+
+	base, err := gomysql.NewConnector(driverCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mysql: failed to open connection: %w", err)
+	}
+	conn := &connector{
+		base:         base,
+		cfg:          driverCfg,
+		credentials:  cfg.credentialProvider(),
+		init:         cfg.SessionInit,
+		onBadConn:    a.stats.retried,
+		tracing:      a.tracing,
+		comments:     newCommenter(cfg.SQLComments),
+		killOnCancel: cfg.KillOnCancel,
+		lifetime:     cfg.ConnMaxAge,
+		jitter:       cfg.ConnMaxAgeJitter,
+	}
+	db := sql.OpenDB(conn)
+
+	// Configure connection pool
+	db.SetMaxOpenConns(cfg.MaxConnections)
+	db.SetMaxIdleConns(cfg.MaxIdle)
+	db.SetConnMaxLifetime(cfg.ConnMaxAge)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	return db, conn, nil
+}
+
 // Close releases database connections.
 func (a *MySQLAdapter) Close() error {
+	defer a.deregisterTLS()
+	a.unobservePools()
+	if a.avail != nil {
+		a.avail.close()
+	}
+	// Pending slow query plans may still be captured on any pool
+	a.slow.wait()
+	a.replicas.close()
 	if a.db != nil {
 		return a.db.Close()
 	}
 	return nil
 }
 
-// Fetch retrieves one or more records from MySQL.
-func (a *MySQLAdapter) Fetch(ctx context.Context, op *adapter.Operation, params map[string]interface{}) ([]interface{}, error) {
-	if a.db == nil {
-		return nil, fmt.Errorf("mysql: adapter not connected")
-	}
-
-	// Replace placeholders in query with positional parameters
-	query, args := a.buildQuery(op.Statement, params)
-
-	// Prepare statement
-	stmt, err := a.db.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("mysql: failed to prepare query: %w", err)
+// deregisterTLS removes the adapter's TLS configurations from the driver.
+func (a *MySQLAdapter) deregisterTLS() {
+	for _, name := range a.tlsNames {
+		gomysql.DeregisterTLSConfig(name)
 	}
-	defer func() { _ = stmt.Close() }()
+	a.tlsNames = nil
+}
 
-	// Execute query
-	rows, err := stmt.QueryContext(ctx, args...)
+// Fetch retrieves one or more records from MySQL.
+func (a *MySQLAdapter) Fetch(ctx context.Context, op *adapter.Operation, params map[string]interface{}) ([]interface{}, error) {
+	ctx, o, err := a.begin(ctx, "Fetch", adapter.OpFetch, fetchMapping(op.Statement))
 	if err != nil {
-		return nil, fmt.Errorf("mysql: query failed: %w", err)
+		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
+	results, err := a.fetch(ctx, op, params)
+	o.end(err, int64(len(results)), 0)
+	return results, err
+}
 
-	// Get column names
-	columns, err := rows.Columns()
+// fetch implements Fetch once the adapter is ready.
+func (a *MySQLAdapter) fetch(ctx context.Context, op *adapter.Operation, params map[string]interface{}) ([]interface{}, error) {
+	// Encode binary identifiers, then replace placeholders in query with
+	// positional parameters
+	params, err := a.encodeParams(op, params)
 	if err != nil {
-		return nil, fmt.Errorf("mysql: failed to get columns: %w", err)
+		return nil, err
 	}
-
-	// Scan results
-	var results []interface{}
-	for rows.Next() {
-		// Create slice of interface{} for scanning
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+	query, args := a.buildQuery(op.Statement, params)
+	query = maxExecutionTime(query, a.queryTimeout(fetchMapping(op.Statement)))
+
+	// Prepare statement on a replica unless the primary is required
+	q, pool, release := a.reader(ctx)
+	defer release()
+	ctx = withServingPool(ctx, pool)
+	call := &Call{Kind: adapter.OpFetch, Operation: op, SQL: query, Args: args}
+	result, err := a.intercept(ctx, call, func(ctx context.Context, call *Call) (*Result, error) {
+		stmt, err := q.PrepareContext(ctx, call.SQL)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: failed to prepare query: %w", err)
 		}
+		defer func() { _ = stmt.Close() }()
 
-		// Scan row
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("mysql: failed to scan row: %w", err)
+		// Execute query
+		rows, err := stmt.QueryContext(ctx, call.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: query failed: %w", err)
 		}
+		defer func() { _ = rows.Close() }()
 
-		// Build result map
-		result := make(map[string]interface{})
-		for i, col := range columns {
-			result[col] = values[i]
+		results, err := a.scanRows(rows, op.Properties)
+		if err != nil {
+			return nil, err
 		}
-
-		results = append(results, result)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("mysql: row iteration error: %w", err)
+		return &Result{Rows: results}, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if we found anything
-	if len(results) == 0 && !op.Multi {
+	if len(result.Rows) == 0 && !op.Multi {
 		return nil, adapter.ErrNotFound
 	}
 
-	return results, nil
+	return result.Rows, nil
 }
 
 // Insert creates new records in MySQL.
 func (a *MySQLAdapter) Insert(ctx context.Context, op *adapter.Operation, objects []interface{}) error {
-	if a.db == nil {
-		return fmt.Errorf("mysql: adapter not connected")
+	ctx, o, err := a.begin(ctx, "Insert", adapter.OpInsert, op.Statement)
+	if err != nil {
+		return err
 	}
+	err = a.insert(ctx, op, objects)
+	var written int64
+	if err == nil {
+		written = int64(len(objects))
+	}
+	o.end(err, 0, written)
+	return err
+}
 
+// insert implements Insert once the adapter is ready.
+func (a *MySQLAdapter) insert(ctx context.Context, op *adapter.Operation, objects []interface{}) error {
 	if len(objects) == 0 {
 		return nil
 	}
-
 	// Handle bulk inserts
 	if op.Bulk && len(objects) > 1 {
 		return a.bulkInsert(ctx, op, objects)
@@ -206,49 +347,86 @@ func (a *MySQLAdapter) singleInsert(ctx context.Context, op *adapter.Operation,
 
 	for _, prop := range op.Properties {
 		// Skip generated fields
-		isGenerated := false
-		for _, gen := range op.Generated {
-			if gen.DataField == prop.DataField {
-				isGenerated = true
-				break
-			}
-		}
-		if isGenerated {
+		if isGeneratedField(op, prop.DataField) {
 			continue
 		}
 
 		if val, ok := data[prop.ObjectField]; ok {
+			val, err := a.encodeID(prop, val)
+			if err != nil {
+				return err
+			}
 			fields = append(fields, prop.DataField)
 			placeholders = append(placeholders, "?")
 			values = append(values, val)
 		}
 	}
 
+	// Add identifiers generated on the client
+	idFields, idValues, err := a.assignClientIDs(op, data)
+	if err != nil {
+		return err
+	}
+	for i := range idFields {
+		fields = append(fields, idFields[i])
+		placeholders = append(placeholders, "?")
+		values = append(values, idValues[i])
+	}
+
+	if err := validateInsert(op, fields); err != nil {
+		return err
+	}
+
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
 		op.Statement,
 		strings.Join(fields, ", "),
 		strings.Join(placeholders, ", "))
 
+	var serverGenerated, clientGenerated []adapter.PropertyMapping
+	for _, gen := range op.Generated {
+		if a.isClientGenerated(gen) {
+			clientGenerated = append(clientGenerated, gen)
+		} else {
+			serverGenerated = append(serverGenerated, gen)
+		}
+	}
+	key, defaults := insertGenerated(op, serverGenerated, len(clientGenerated) > 0)
+	defaults = a.refetchFields(op, defaults)
+
+	// Pin a connection so server defaults are read back from the same session
+	w, err := a.openWrite(ctx, len(defaults) > 0, false)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+	q := w.q
+
 	// Execute insert
-	result, err := a.db.ExecContext(ctx, query, values...)
+	result, err := a.execCall(ctx, q, &Call{Kind: adapter.OpInsert, Operation: op, SQL: query, Args: values})
 	if err != nil {
 		return fmt.Errorf("mysql: insert failed: %w", err)
 	}
+	if _, err := a.warnings(ctx, w); err != nil {
+		return err
+	}
 
-	// Handle generated IDs
-	if len(op.Generated) > 0 {
-		lastID, err := result.LastInsertId()
+	// Set generated ID back to object
+	if key != nil {
+		data[key.ObjectField] = result.LastInsertID
+	}
+
+	// Re-fetch columns filled by server defaults
+	if len(defaults) > 0 {
+		whereClauses, whereValues, err := a.insertedRowWhere(op, key, clientGenerated, data)
 		if err != nil {
-			return fmt.Errorf("mysql: failed to get last insert ID: %w", err)
+			return err
 		}
-
-		// Set generated ID back to object
-		for _, gen := range op.Generated {
-			data[gen.ObjectField] = lastID
+		if err := a.refetch(ctx, q, op, defaults, whereClauses, whereValues, data); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	return a.commitWrite(ctx, w)
 }
 
 // bulkInsert handles inserting multiple records efficiently.
@@ -266,25 +444,27 @@ func (a *MySQLAdapter) bulkInsert(ctx context.Context, op *adapter.Operation, ob
 	var fields []string
 	for _, prop := range op.Properties {
 		// Skip generated fields
-		isGenerated := false
-		for _, gen := range op.Generated {
-			if gen.DataField == prop.DataField {
-				isGenerated = true
-				break
-			}
-		}
-		if !isGenerated {
+		if !isGeneratedField(op, prop.DataField) {
 			if _, ok := firstObj[prop.ObjectField]; ok {
 				fields = append(fields, prop.DataField)
 			}
 		}
 	}
+	for _, gen := range op.Generated {
+		if a.isClientGenerated(gen) {
+			fields = append(fields, gen.DataField)
+		}
+	}
+
+	if err := validateInsert(op, fields); err != nil {
+		return err
+	}
 
 	// Build bulk INSERT statement
 	var valueSets []string
 	var values []interface{}
 
-	for _, obj := range objects {
+	for i, obj := range objects {
 		data, ok := obj.(map[string]interface{})
 		if !ok {
 			return fmt.Errorf("mysql: object must be map[string]interface{}")
@@ -292,20 +472,32 @@ func (a *MySQLAdapter) bulkInsert(ctx context.Context, op *adapter.Operation, ob
 
 		var placeholders []string
 		for _, prop := range op.Properties {
-			isGenerated := false
-			for _, gen := range op.Generated {
-				if gen.DataField == prop.DataField {
-					isGenerated = true
-					break
-				}
-			}
-			if !isGenerated {
+			if !isGeneratedField(op, prop.DataField) {
 				if val, ok := data[prop.ObjectField]; ok {
+					val, err := a.encodeID(prop, val)
+					if err != nil {
+						return err
+					}
 					placeholders = append(placeholders, "?")
 					values = append(values, val)
 				}
 			}
 		}
+		_, idValues, err := a.assignClientIDs(op, data)
+		if err != nil {
+			return err
+		}
+		for _, val := range idValues {
+			placeholders = append(placeholders, "?")
+			values = append(values, val)
+		}
+		if len(placeholders) != len(fields) {
+			return &ValidationError{
+				Operation: adapter.OpInsert,
+				Table:     op.Statement,
+				Reason:    fmt.Sprintf("object %d does not provide the same fields as the first object", i),
+			}
+		}
 		valueSets = append(valueSets, "("+strings.Join(placeholders, ", ")+")")
 	}
 
@@ -314,40 +506,85 @@ func (a *MySQLAdapter) bulkInsert(ctx context.Context, op *adapter.Operation, ob
 		strings.Join(fields, ", "),
 		strings.Join(valueSets, ", "))
 
-	// Execute bulk insert
-	_, err := a.db.ExecContext(ctx, query, values...)
+	w, err := a.openWrite(ctx, false, false)
 	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	// Execute bulk insert
+	if _, err := a.execCall(ctx, w.q, &Call{Kind: adapter.OpInsert, Operation: op, SQL: query, Args: values}); err != nil {
 		return fmt.Errorf("mysql: bulk insert failed: %w", err)
 	}
+	if _, err := a.warnings(ctx, w); err != nil {
+		return err
+	}
 
-	return nil
+	return a.commitWrite(ctx, w)
+}
+
+// UpdateResult reports the outcome of an update.
+// MySQL only counts rows whose values actually changed as affected, so an
+// update that writes a row's current values matches the row without changing it.
+type UpdateResult struct {
+	// RowsMatched is the number of rows that satisfied the WHERE clause.
+	RowsMatched int64
+
+	// RowsChanged is the number of rows whose values were modified.
+	RowsChanged int64
+
+	// Warnings holds the warnings MySQL raised, when they are captured.
+	Warnings []Warning
 }
 
 // Update modifies existing records in MySQL.
 func (a *MySQLAdapter) Update(ctx context.Context, op *adapter.Operation, objects []interface{}) error {
-	if a.db == nil {
-		return fmt.Errorf("mysql: adapter not connected")
+	_, err := a.UpdateWithResult(ctx, op, objects)
+	return err
+}
+
+// UpdateWithResult modifies existing records in MySQL and reports how many
+// rows were matched and changed. A row that exists but already holds the
+// submitted values is counted as matched and does not produce ErrNotFound.
+func (a *MySQLAdapter) UpdateWithResult(ctx context.Context, op *adapter.Operation, objects []interface{}) (*UpdateResult, error) {
+	ctx, o, err := a.begin(ctx, "Update", adapter.OpUpdate, op.Statement)
+	if err != nil {
+		return nil, err
+	}
+	result, err := a.updateWithResult(ctx, op, objects)
+	var written int64
+	if result != nil {
+		written = result.RowsChanged
 	}
+	o.end(err, 0, written)
+	return result, err
+}
 
+// updateWithResult implements UpdateWithResult once the adapter is ready.
+func (a *MySQLAdapter) updateWithResult(ctx context.Context, op *adapter.Operation, objects []interface{}) (*UpdateResult, error) {
+	total := &UpdateResult{}
 	if len(objects) == 0 {
-		return nil
+		return total, nil
 	}
-
 	// Handle each object
 	for _, obj := range objects {
-		if err := a.singleUpdate(ctx, op, obj); err != nil {
-			return err
+		res, err := a.singleUpdate(ctx, op, obj)
+		if err != nil {
+			return total, err
 		}
+		total.RowsMatched += res.RowsMatched
+		total.RowsChanged += res.RowsChanged
+		total.Warnings = append(total.Warnings, res.Warnings...)
 	}
 
-	return nil
+	return total, nil
 }
 
 // singleUpdate handles updating a single record.
-func (a *MySQLAdapter) singleUpdate(ctx context.Context, op *adapter.Operation, obj interface{}) error {
+func (a *MySQLAdapter) singleUpdate(ctx context.Context, op *adapter.Operation, obj interface{}) (*UpdateResult, error) {
 	data, ok := obj.(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("mysql: object must be map[string]interface{}")
+		return nil, fmt.Errorf("mysql: object must be map[string]interface{}")
 	}
 
 	// Build UPDATE statement
@@ -355,77 +592,246 @@ func (a *MySQLAdapter) singleUpdate(ctx context.Context, op *adapter.Operation,
 	var values []interface{}
 
 	for _, prop := range op.Properties {
-		// Skip identifier fields
-		isIdentifier := false
-		for _, id := range op.Identifier {
-			if id.DataField == prop.DataField {
-				isIdentifier = true
-				break
-			}
-		}
-		if isIdentifier {
+		// Skip identifier, version and server-maintained fields
+		if isIdentifierField(op, prop.DataField) || isConditionField(op, prop.DataField) || isGeneratedField(op, prop.DataField) {
 			continue
 		}
 
 		if val, ok := data[prop.ObjectField]; ok {
+			val, err := a.encodeID(prop, val)
+			if err != nil {
+				return nil, err
+			}
 			setClauses = append(setClauses, prop.DataField+" = ?")
 			values = append(values, val)
 		}
 	}
 
 	// Build WHERE clause
+	idClauses, idValues, err := identifierWhere(adapter.OpUpdate, op, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.encodeIDs(op.Identifier, idValues); err != nil {
+		return nil, err
+	}
+	whereClauses := append([]string{}, idClauses...)
+	whereValues := append([]interface{}{}, idValues...)
+
+	// Add optimistic locking conditions and bump their versions
+	versions, err := lockVersions(op, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if v.next != nil {
+			setClauses = append(setClauses, v.field.DataField+" = "+v.field.DataField+" + 1")
+		}
+		whereClauses = append(whereClauses, v.field.DataField+" = ?")
+		whereValues = append(whereValues, v.current)
+	}
+	values = append(values, whereValues...)
+
+	if err := validateUpdate(op, setClauses, idClauses); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		op.Statement,
+		strings.Join(setClauses, ", "),
+		strings.Join(whereClauses, " AND "))
+
+	// Pin a connection so ON UPDATE columns are read back from the same session
+	refetched := a.refetchFields(op, op.Generated)
+	pin := len(refetched) > 0
+
+	attempt := func(inTx bool) (*UpdateResult, error) {
+		w, err := a.openWrite(ctx, pin, inTx)
+		if err != nil {
+			return nil, err
+		}
+		defer w.close()
+		q := w.q
+
+		// Execute update
+		result, err := a.execCall(ctx, q, &Call{Kind: adapter.OpUpdate, Operation: op, SQL: query, Args: values})
+		if err != nil {
+			return nil, fmt.Errorf("mysql: update failed: %w", err)
+		}
+		changed := result.RowsAffected
+		if changed == 0 && w.tx == nil {
+			return nil, errUnchanged
+		}
+		warnings, err := a.warnings(ctx, w)
+		if err != nil {
+			return nil, err
+		}
+
+		// MySQL only counts changed rows as affected. A row that already held
+		// the values is still locked by the update, so count the matched rows
+		// in its transaction.
+		matched := changed
+		if changed == 0 {
+			if matched, err = a.count(ctx, q, op, whereClauses, whereValues); err != nil {
+				return nil, err
+			}
+		}
+
+		if matched == 0 {
+			if len(versions) == 0 {
+				return nil, adapter.ErrNotFound
+			}
+			// The identifier or the version did not match; probe to tell them apart
+			exists, err := a.exists(ctx, q, op, idClauses, idValues)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, adapter.ErrNotFound
+			}
+			return nil, ErrConcurrentModification
+		}
+
+		// Write the bumped versions back to the object
+		for _, v := range versions {
+			if v.next != nil {
+				data[v.field.ObjectField] = v.next
+			}
+		}
+
+		// Re-fetch columns maintained by the server (ON UPDATE CURRENT_TIMESTAMP)
+		if len(refetched) > 0 {
+			if err := a.refetch(ctx, q, op, refetched, idClauses, idValues, data); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := a.commitWrite(ctx, w); err != nil {
+			return nil, err
+		}
+		return &UpdateResult{RowsMatched: matched, RowsChanged: changed, Warnings: warnings}, nil
+	}
+
+	res, err := attempt(false)
+	if errors.Is(err, errUnchanged) {
+		// Nothing changed outside a transaction, and the row may have been
+		// written since. Repeat the update in a transaction so the rows it
+		// matched are counted while it holds them.
+		res, err = attempt(true)
+	}
+	return res, err
+}
+
+// errUnchanged reports an update outside a transaction that changed no rows.
+var errUnchanged = errors.New("mysql: update changed no rows")
+
+// identifierWhere builds the WHERE clauses locating a record by its identifier
+// fields. Identifier fields missing from data fail the kind of statement being
+// built with a *ValidationError naming them.
+func identifierWhere(kind adapter.OperationType, op *adapter.Operation, data map[string]interface{}) ([]string, []interface{}, error) {
 	var whereClauses []string
+	var values []interface{}
+	var missing []string
+
 	for _, id := range op.Identifier {
 		if val, ok := data[id.ObjectField]; ok {
 			whereClauses = append(whereClauses, id.DataField+" = ?")
 			values = append(values, val)
 		} else {
-			return fmt.Errorf("mysql: missing identifier field: %s", id.ObjectField)
+			missing = append(missing, id.ObjectField)
 		}
 	}
-
-	// Add optimistic locking condition if present
-	for _, cond := range op.Condition {
-		if val, ok := data[cond.ObjectField]; ok {
-			whereClauses = append(whereClauses, cond.DataField+" = ?")
-			values = append(values, val)
+	if len(missing) > 0 {
+		return nil, nil, &ValidationError{
+			Operation: kind,
+			Table:     op.Statement,
+			Reason:    "object is missing identifier fields",
+			Fields:    missing,
 		}
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
-		op.Statement,
-		strings.Join(setClauses, ", "),
-		strings.Join(whereClauses, " AND "))
+	return whereClauses, values, nil
+}
 
-	// Execute update
-	result, err := a.db.ExecContext(ctx, query, values...)
-	if err != nil {
-		return fmt.Errorf("mysql: update failed: %w", err)
+// isIdentifierField reports whether dataField is one of the operation's identifiers.
+func isIdentifierField(op *adapter.Operation, dataField string) bool {
+	for _, id := range op.Identifier {
+		if id.DataField == dataField {
+			return true
+		}
 	}
+	return false
+}
 
-	// Check if any rows were affected
-	rowsAffected, err := result.RowsAffected()
+// exists reports whether a row matching the WHERE clauses is present in the
+// operation's table. The probe runs through the interceptor chain, so a
+// rewriting interceptor scopes it like the statement it follows.
+func (a *MySQLAdapter) exists(ctx context.Context, q queryer, op *adapter.Operation, whereClauses []string, values []interface{}) (bool, error) {
+	call := &Call{Kind: adapter.OpFetch, Operation: op, SQL: existsQuery(op.Statement, whereClauses), Args: values}
+	result, err := a.queryCall(ctx, q, call, nil)
 	if err != nil {
-		return fmt.Errorf("mysql: failed to get affected rows: %w", err)
+		return false, fmt.Errorf("mysql: existence check failed: %w", err)
 	}
+	return len(result.Rows) > 0, nil
+}
 
-	if rowsAffected == 0 {
-		return adapter.ErrNotFound
+// count returns the number of rows matching the WHERE clauses in the
+// operation's table, running through the interceptor chain like exists.
+func (a *MySQLAdapter) count(ctx context.Context, q queryer, op *adapter.Operation, whereClauses []string, values []interface{}) (int64, error) {
+	call := &Call{Kind: adapter.OpFetch, Operation: op, SQL: countQuery(op.Statement, whereClauses), Args: values}
+	result, err := a.queryCall(ctx, q, call, nil)
+	if err != nil {
+		return 0, fmt.Errorf("mysql: row count failed: %w", err)
 	}
+	if len(result.Rows) == 0 {
+		return 0, nil
+	}
+	row, _ := result.Rows[0].(map[string]interface{})
+	switch n := row["matched"].(type) {
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	case []byte:
+		return strconv.ParseInt(string(n), 10, 64)
+	}
+	return 0, fmt.Errorf("mysql: unexpected row count %T", row["matched"])
+}
 
-	return nil
+// countQuery builds a statement counting the rows matching the WHERE clauses.
+func countQuery(table string, whereClauses []string) string {
+	return fmt.Sprintf("SELECT COUNT(*) AS matched FROM %s WHERE %s",
+		table,
+		strings.Join(whereClauses, " AND "))
+}
+
+// existsQuery builds a statement selecting at most one row matching the WHERE clauses.
+func existsQuery(table string, whereClauses []string) string {
+	return fmt.Sprintf("SELECT 1 FROM %s WHERE %s LIMIT 1",
+		table,
+		strings.Join(whereClauses, " AND "))
 }
 
 // Delete removes records from MySQL.
 func (a *MySQLAdapter) Delete(ctx context.Context, op *adapter.Operation, identifiers []interface{}) error {
-	if a.db == nil {
-		return fmt.Errorf("mysql: adapter not connected")
+	ctx, o, err := a.begin(ctx, "Delete", adapter.OpDelete, op.Statement)
+	if err != nil {
+		return err
 	}
+	err = a.delete(ctx, op, identifiers)
+	var written int64
+	if err == nil {
+		written = int64(len(identifiers))
+	}
+	o.end(err, 0, written)
+	return err
+}
 
+// delete implements Delete once the adapter is ready.
+func (a *MySQLAdapter) delete(ctx context.Context, op *adapter.Operation, identifiers []interface{}) error {
 	if len(identifiers) == 0 {
 		return nil
 	}
-
 	// Handle each identifier
 	for _, id := range identifiers {
 		if err := a.singleDelete(ctx, op, id); err != nil {
@@ -445,81 +851,169 @@ func (a *MySQLAdapter) singleDelete(ctx context.Context, op *adapter.Operation,
 	switch id := identifier.(type) {
 	case map[string]interface{}:
 		// Complex identifier with multiple fields
-		for _, idField := range op.Identifier {
-			if val, ok := id[idField.ObjectField]; ok {
-				whereClauses = append(whereClauses, idField.DataField+" = ?")
-				values = append(values, val)
-			} else {
-				return fmt.Errorf("mysql: missing identifier field: %s", idField.ObjectField)
-			}
+		var err error
+		whereClauses, values, err = identifierWhere(adapter.OpDelete, op, id)
+		if err != nil {
+			return err
 		}
 	default:
 		// Simple identifier (single field)
 		if len(op.Identifier) != 1 {
-			return fmt.Errorf("mysql: simple identifier requires exactly one identifier field")
+			fields := make([]string, len(op.Identifier))
+			for i, f := range op.Identifier {
+				fields[i] = f.ObjectField
+			}
+			return &ValidationError{
+				Operation: adapter.OpDelete,
+				Table:     op.Statement,
+				Reason:    fmt.Sprintf("a single identifier value requires exactly one identifier field, got %d", len(op.Identifier)),
+				Fields:    fields,
+			}
 		}
 		whereClauses = append(whereClauses, op.Identifier[0].DataField+" = ?")
 		values = append(values, identifier)
 	}
 
+	if err := a.encodeIDs(op.Identifier, values); err != nil {
+		return err
+	}
+
+	if err := validateDelete(op, whereClauses); err != nil {
+		return err
+	}
+
 	query := fmt.Sprintf("DELETE FROM %s WHERE %s",
 		op.Statement,
 		strings.Join(whereClauses, " AND "))
 
-	// Execute delete
-	result, err := a.db.ExecContext(ctx, query, values...)
+	// Pin a connection when the write's GTID is tracked
+	var q queryer = a.db
+	conn, err := a.trackedConn(ctx)
 	if err != nil {
-		return fmt.Errorf("mysql: delete failed: %w", err)
+		return err
+	}
+	if conn != nil {
+		defer func() { _ = conn.Close() }()
+		q = conn
 	}
 
-	// Check if any rows were affected
-	rowsAffected, err := result.RowsAffected()
+	// Execute delete
+	result, err := a.execCall(ctx, q, &Call{Kind: adapter.OpDelete, Operation: op, SQL: query, Args: values})
 	if err != nil {
-		return fmt.Errorf("mysql: failed to get affected rows: %w", err)
+		return fmt.Errorf("mysql: delete failed: %w", err)
 	}
 
-	if rowsAffected == 0 {
+	// Check if any rows were affected
+	if result.RowsAffected == 0 {
 		return adapter.ErrNotFound
 	}
 
+	a.trackWrite(ctx, conn)
 	return nil
 }
 
 // Execute runs custom SQL statements or stored procedures.
 func (a *MySQLAdapter) Execute(ctx context.Context, action *adapter.Action, params map[string]interface{}) (interface{}, error) {
-	if a.db == nil {
-		return nil, fmt.Errorf("mysql: adapter not connected")
+	ctx, o, err := a.begin(ctx, "Execute", adapter.OpAction, action.Name, attrAction.String(action.Name))
+	if err != nil {
+		return nil, err
 	}
+	result, err := a.execute(ctx, action, params)
+	var read, written int64
+	switch r := result.(type) {
+	case []interface{}:
+		read = int64(len(r))
+	case map[string]interface{}:
+		written, _ = r["rows_affected"].(int64)
+	}
+	o.end(err, read, written)
+	return result, err
+}
 
+// execute implements Execute once the adapter is ready.
+func (a *MySQLAdapter) execute(ctx context.Context, action *adapter.Action, params map[string]interface{}) (interface{}, error) {
 	// Replace placeholders in statement
 	query, args := a.buildQuery(action.Statement, params)
+	query = maxExecutionTime(query, a.queryTimeout(action.Name))
 
 	// Determine if this is a query or exec based on Result mapping
 	if action.Result != nil {
 		// Execute query (SELECT, CALL with results)
-		return a.executeQuery(ctx, query, args)
+		if a.isReadOnlyAction(action) {
+			q, pool, release := a.reader(ctx)
+			defer release()
+			return a.executeQuery(withServingPool(ctx, pool), q, action, query, args)
+		}
+		var q queryer = a.db
+		conn, err := a.trackedConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			defer func() { _ = conn.Close() }()
+			q = conn
+		}
+		rows, err := a.executeQuery(ctx, q, action, query, args)
+		if err != nil {
+			return nil, err
+		}
+		a.trackWrite(ctx, conn)
+		return rows, nil
 	}
 
 	// Execute statement (INSERT, UPDATE, DELETE, CALL without results)
-	result, err := a.db.ExecContext(ctx, query, args...)
+	w, err := a.openWrite(ctx, false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	result, err := a.execCall(ctx, w.q, &Call{Kind: adapter.OpAction, Action: action, SQL: query, Args: args})
 	if err != nil {
 		return nil, fmt.Errorf("mysql: execute failed: %w", err)
 	}
+	warnings, err := a.warnings(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.commitWrite(ctx, w); err != nil {
+		return nil, err
+	}
 
-	rowsAffected, _ := result.RowsAffected()
-	return map[string]interface{}{
-		"rows_affected": rowsAffected,
-	}, nil
+	res := map[string]interface{}{
+		"rows_affected": result.RowsAffected,
+	}
+	if len(warnings) > 0 {
+		res["warnings"] = warnings
+	}
+	return res, nil
 }
 
 // executeQuery executes a query and returns results.
-func (a *MySQLAdapter) executeQuery(ctx context.Context, query string, args []interface{}) (interface{}, error) {
-	rows, err := a.db.QueryContext(ctx, query, args...)
+func (a *MySQLAdapter) executeQuery(ctx context.Context, db queryer, action *adapter.Action, query string, args []interface{}) (interface{}, error) {
+	call := &Call{Kind: adapter.OpAction, Action: action, SQL: query, Args: args}
+	result, err := a.intercept(ctx, call, func(ctx context.Context, call *Call) (*Result, error) {
+		rows, err := db.QueryContext(ctx, call.SQL, call.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: query failed: %w", err)
+		}
+		defer func() { _ = rows.Close() }()
+
+		results, err := a.scanRows(rows, action.Result.Properties)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Rows: results}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("mysql: query failed: %w", err)
+		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
+	return result.Rows, nil
+}
 
+// scanRows reads all rows into maps of column values, decoding binary
+// identifiers listed in props.
+func (a *MySQLAdapter) scanRows(rows *sql.Rows, props []adapter.PropertyMapping) ([]interface{}, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
@@ -529,21 +1023,28 @@ func (a *MySQLAdapter) executeQuery(ctx context.Context, query string, args []in
 	// Scan results
 	var results []interface{}
 	for rows.Next() {
+		// Create slice of interface{} for scanning
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
 		for i := range values {
 			valuePtrs[i] = &values[i]
 		}
 
+		// Scan row
 		if err := rows.Scan(valuePtrs...); err != nil {
 			return nil, fmt.Errorf("mysql: failed to scan row: %w", err)
 		}
 
+		// Build result map
 		result := make(map[string]interface{})
 		for i, col := range columns {
 			result[col] = values[i]
 		}
 
+		if err := a.decodeIDs(props, result); err != nil {
+			return nil, err
+		}
+
 		results = append(results, result)
 	}
 
@@ -570,21 +1071,3 @@ func (a *MySQLAdapter) buildQuery(query string, params map[string]interface{}) (
 
 	return result, args
 }
-
-// Helper functions for config extraction
-func getStringConfig(config map[string]interface{}, key, defaultValue string) string {
-	if val, ok := config[key].(string); ok {
-		return val
-	}
-	return defaultValue
-}
-
-func getIntConfig(config map[string]interface{}, key string, defaultValue int) int {
-	if val, ok := config[key].(int); ok {
-		return val
-	}
-	if val, ok := config[key].(float64); ok {
-		return int(val)
-	}
-	return defaultValue
-}
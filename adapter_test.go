@@ -2,6 +2,11 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/toutaio/toutago-datamapper/adapter"
@@ -82,33 +87,35 @@ func TestMySQLAdapter_BuildQuery(t *testing.T) {
 	}
 }
 
-func TestMySQLAdapter_ConfigHelpers(t *testing.T) {
-	config := map[string]interface{}{
-		"string_val": "test",
-		"int_val":    42,
-		"float_val":  3.14,
+func TestMySQLAdapter_ConfigValues(t *testing.T) {
+	// Test stringValue
+	if val, err := stringValue("host", "test"); err != nil || val != "test" {
+		t.Errorf("expected 'test', got '%s' (%v)", val, err)
+	}
+
+	if _, err := stringValue("host", 42); err == nil {
+		t.Error("expected error for non-string value")
 	}
 
-	// Test getStringConfig
-	if val := getStringConfig(config, "string_val", "default"); val != "test" {
-		t.Errorf("expected 'test', got '%s'", val)
+	// Test intValue
+	if val, err := intValue("port", 42); err != nil || val != 42 {
+		t.Errorf("expected 42, got %d (%v)", val, err)
 	}
 
-	if val := getStringConfig(config, "missing", "default"); val != "default" {
-		t.Errorf("expected 'default', got '%s'", val)
+	if val, err := intValue("port", float64(3)); err != nil || val != 3 {
+		t.Errorf("expected 3, got %d (%v)", val, err)
 	}
 
-	// Test getIntConfig
-	if val := getIntConfig(config, "int_val", 0); val != 42 {
-		t.Errorf("expected 42, got %d", val)
+	if val, err := intValue("port", "20"); err != nil || val != 20 {
+		t.Errorf("expected 20, got %d (%v)", val, err)
 	}
 
-	if val := getIntConfig(config, "float_val", 0); val != 3 {
-		t.Errorf("expected 3, got %d", val)
+	if _, err := intValue("port", 3.14); err == nil {
+		t.Error("expected error for fractional value")
 	}
 
-	if val := getIntConfig(config, "missing", 99); val != 99 {
-		t.Errorf("expected 99, got %d", val)
+	if _, err := intValue("port", "twenty"); err == nil {
+		t.Error("expected error for non-numeric string")
 	}
 }
 
@@ -154,3 +161,98 @@ func TestMySQLAdapter_NotConnectedErrors(t *testing.T) {
 		t.Error("expected error when executing without connection")
 	}
 }
+
+func TestIdentifierWhere(t *testing.T) {
+	op := &adapter.Operation{
+		Identifier: []adapter.PropertyMapping{
+			{ObjectField: "TenantID", DataField: "tenant_id"},
+			{ObjectField: "ID", DataField: "id"},
+		},
+	}
+
+	clauses, values, err := identifierWhere(adapter.OpUpdate, op, map[string]interface{}{"ID": 7, "TenantID": 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(clauses, " AND "); got != "tenant_id = ? AND id = ?" {
+		t.Errorf("unexpected where clause '%s'", got)
+	}
+	if len(values) != 2 || values[0] != 2 || values[1] != 7 {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	_, _, err = identifierWhere(adapter.OpUpdate, op, map[string]interface{}{"ID": 7})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0] != "TenantID" {
+		t.Errorf("expected validation error naming TenantID, got %v", err)
+	}
+}
+
+func TestExistsQuery(t *testing.T) {
+	got := existsQuery("users", []string{"id = ?", "version = ?"})
+	expected := "SELECT 1 FROM users WHERE id = ? AND version = ? LIMIT 1"
+	if got != expected {
+		t.Errorf("expected query '%s', got '%s'", expected, got)
+	}
+}
+
+// newScriptAdapter returns an adapter whose statements are answered by script.
+func newScriptAdapter(t *testing.T, script *scriptConnector, opts ...Option) *MySQLAdapter {
+	t.Helper()
+	a, err := NewMySQLAdapterWithOptions(opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+	t.Cleanup(func() { _ = a.db.Close() })
+	return a
+}
+
+func TestMySQLAdapter_UpdateMatchedRows(t *testing.T) {
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+	}
+	update := "UPDATE users SET name = ? WHERE id = ?"
+	count := "SELECT COUNT(*) AS matched FROM users WHERE id = ?"
+	script := &scriptConnector{results: map[string]fakeResult{
+		update: {exec: fakeExec{affected: 1}},
+	}}
+	a := newScriptAdapter(t, script)
+	obj := []interface{}{map[string]interface{}{"ID": 1, "Name": "Ann"}}
+
+	// A changed row needs no further statements
+	res, err := a.UpdateWithResult(context.Background(), op, obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.RowsMatched != 1 || res.RowsChanged != 1 {
+		t.Errorf("expected 1 matched and changed row, got %+v", res)
+	}
+	if got := script.executed(); len(got) != 1 {
+		t.Errorf("expected only the update, got %v", got)
+	}
+
+	// The row already holds the values: the update is repeated in a
+	// transaction and the matched rows counted there
+	script.queries = nil
+	script.results[update] = fakeResult{exec: fakeExec{}}
+	script.results[count] = fakeResult{columns: []string{"matched"}, rows: [][]driver.Value{{int64(1)}}}
+	res, err = a.UpdateWithResult(context.Background(), op, obj)
+	if err != nil {
+		t.Fatalf("expected no-op update to succeed, got %v", err)
+	}
+	if res.RowsMatched != 1 || res.RowsChanged != 0 {
+		t.Errorf("expected 1 matched and 0 changed rows, got %+v", res)
+	}
+	if got, want := script.executed(), []string{update, update, count, "COMMIT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	script.results[count] = fakeResult{columns: []string{"matched"}, rows: [][]driver.Value{{[]byte("0")}}}
+	err = a.Update(context.Background(), op, []interface{}{map[string]interface{}{"ID": 2, "Name": "Bob"}})
+	if !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing row, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// ErrUnavailable is returned by operations on a lazily connected adapter
+// while the database cannot be reached. It matches adapter.ErrConnection with
+// errors.Is.
+var ErrUnavailable = &adapter.AdapterError{
+	Code:    "CONNECTION",
+	Message: "database unavailable",
+	Cause:   adapter.ErrConnection,
+}
+
+// State is the availability of the database behind a lazily connected adapter.
+type State int
+
+// Availability states.
+const (
+	// StateConnecting means the database has not been reached yet.
+	StateConnecting State = iota
+
+	// StateReady means the database is reachable.
+	StateReady
+
+	// StateUnavailable means the database was reachable but connections
+	// are failing; the adapter keeps probing in the background.
+	StateUnavailable
+)
+
+// String returns the state name.
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateReady:
+		return "ready"
+	case StateUnavailable:
+		return "unavailable"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// StateFunc is called on every availability transition with the new state
+// and, when the database is not ready, the error that caused it.
+type StateFunc func(state State, err error)
+
+// availability tracks whether the database is reachable and probes it in
+// the background with exponential backoff while it is not.
+type availability struct {
+	ping       func(ctx context.Context) error
+	onChange   StateFunc
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	state   State
+	lastErr error
+	ready   chan struct{}
+	probing bool
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// newAvailability returns a tracker in the connecting state.
+func newAvailability(ping func(ctx context.Context) error, onChange StateFunc, minBackoff, maxBackoff time.Duration) *availability {
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+	return &availability{
+		ping:       ping,
+		onChange:   onChange,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		state:      StateConnecting,
+		ready:      make(chan struct{}),
+		stop:       make(chan struct{}),
+	}
+}
+
+// check returns ErrUnavailable, wrapping the last connection error, unless
+// the database is ready.
+func (v *availability) check() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.state == StateReady {
+		return nil
+	}
+	if v.lastErr != nil {
+		return fmt.Errorf("mysql: %w: %v", ErrUnavailable, v.lastErr)
+	}
+	return fmt.Errorf("mysql: %w", ErrUnavailable)
+}
+
+// readyChan returns a channel closed once the database is ready.
+func (v *availability) readyChan() <-chan struct{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.ready
+}
+
+// current returns the current state.
+func (v *availability) current() State {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.state
+}
+
+// succeeded records a successful connection.
+func (v *availability) succeeded() {
+	v.mu.Lock()
+	if v.state == StateReady {
+		v.mu.Unlock()
+		return
+	}
+	v.state = StateReady
+	v.lastErr = nil
+	close(v.ready)
+	v.mu.Unlock()
+
+	v.notify(StateReady, nil)
+}
+
+// failed records a connection-class error and starts probing in the
+// background until the database is reachable again.
+func (v *availability) failed(err error) {
+	v.mu.Lock()
+	select {
+	case <-v.stop:
+		v.mu.Unlock()
+		return
+	default:
+	}
+
+	v.lastErr = err
+	changed := v.state == StateReady
+	if changed {
+		v.state = StateUnavailable
+		v.ready = make(chan struct{})
+	}
+	v.mu.Unlock()
+
+	if changed {
+		v.notify(StateUnavailable, err)
+	}
+	v.start()
+}
+
+// start begins probing in the background unless a probe is running.
+func (v *availability) start() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.probing {
+		return
+	}
+	select {
+	case <-v.stop:
+		return
+	default:
+	}
+	v.probing = true
+	v.done.Add(1)
+	go v.probe()
+}
+
+// probe pings the database with exponential backoff until it responds or
+// the tracker is closed.
+func (v *availability) probe() {
+	defer v.done.Done()
+
+	backoff := v.minBackoff
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), v.maxBackoff)
+		err := v.ping(ctx)
+		cancel()
+
+		v.mu.Lock()
+		if err == nil {
+			v.probing = false
+			v.mu.Unlock()
+			v.succeeded()
+			return
+		}
+		v.lastErr = err
+		v.mu.Unlock()
+
+		timer := time.NewTimer(backoff - jitterDuration(backoff/2))
+		select {
+		case <-v.stop:
+			timer.Stop()
+			v.mu.Lock()
+			v.probing = false
+			v.mu.Unlock()
+			return
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > v.maxBackoff {
+			backoff = v.maxBackoff
+		}
+	}
+}
+
+// close stops background probing and waits for it to finish.
+func (v *availability) close() {
+	v.mu.Lock()
+	select {
+	case <-v.stop:
+	default:
+		close(v.stop)
+	}
+	v.mu.Unlock()
+	v.done.Wait()
+}
+
+// notify calls the state callback, if any.
+func (v *availability) notify(state State, err error) {
+	if v.onChange != nil {
+		v.onChange(state, err)
+	}
+}
+
+// checkReady returns an error if the adapter cannot run operations. An
+// operation let through must report its outcome to the breaker.
+func (a *MySQLAdapter) checkReady() error {
+	if a.db == nil {
+		return fmt.Errorf("mysql: adapter not connected")
+	}
+	if a.avail != nil {
+		if err := a.avail.check(); err != nil {
+			return err
+		}
+	}
+	return a.breaker.allow()
+}
+
+// Ready returns a channel that is closed once the database is reachable.
+// For an eagerly connected adapter it is closed as soon as Connect returns.
+// After the database becomes unavailable again, a new channel is returned.
+func (a *MySQLAdapter) Ready() <-chan struct{} {
+	if a.avail != nil {
+		return a.avail.readyChan()
+	}
+	ch := make(chan struct{})
+	if a.db != nil {
+		close(ch)
+	}
+	return ch
+}
+
+// State returns the availability of the database.
+func (a *MySQLAdapter) State() State {
+	if a.avail != nil {
+		return a.avail.current()
+	}
+	if a.db != nil {
+		return StateReady
+	}
+	return StateConnecting
+}
@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestAvailability_Transitions(t *testing.T) {
+	var mu sync.Mutex
+	pingErr := errors.New("connection refused")
+	var states []State
+	v := newAvailability(
+		func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return pingErr
+		},
+		func(state State, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, state)
+		},
+		time.Millisecond, 5*time.Millisecond,
+	)
+	defer v.close()
+
+	v.start()
+	ready := v.readyChan()
+	time.Sleep(20 * time.Millisecond)
+	if err := v.check(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable while connecting, got %v", err)
+	}
+	if v.current() != StateConnecting {
+		t.Errorf("expected connecting, got %s", v.current())
+	}
+
+	mu.Lock()
+	pingErr = nil
+	mu.Unlock()
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("expected ready channel to close once ping succeeds")
+	}
+	if err := v.check(); err != nil {
+		t.Errorf("expected no error when ready, got %v", err)
+	}
+
+	mu.Lock()
+	pingErr = errors.New("server has gone away")
+	mu.Unlock()
+	v.failed(pingErr)
+	if v.current() != StateUnavailable {
+		t.Errorf("expected unavailable after failure, got %s", v.current())
+	}
+	select {
+	case <-v.readyChan():
+		t.Error("expected a new open ready channel after failure")
+	default:
+	}
+
+	mu.Lock()
+	got := append([]State(nil), states...)
+	mu.Unlock()
+	want := []State{StateReady, StateUnavailable}
+	if len(got) < 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected transitions %v, got %v", want, got)
+	}
+}
+
+func TestAvailability_CloseStopsProbing(t *testing.T) {
+	pings := 0
+	v := newAvailability(func(ctx context.Context) error {
+		pings++
+		return errors.New("down")
+	}, nil, time.Millisecond, time.Millisecond)
+
+	v.start()
+	time.Sleep(10 * time.Millisecond)
+	v.close()
+	after := pings
+	time.Sleep(10 * time.Millisecond)
+	if pings != after {
+		t.Error("expected probing to stop after close")
+	}
+	v.failed(errors.New("down"))
+	v.done.Wait()
+}
+
+func TestMySQLAdapter_LazyConnect(t *testing.T) {
+	a, err := NewMySQLAdapterWithOptions(
+		WithHost("127.0.0.1"),
+		WithPort(1),
+		WithLazyConnect(nil),
+		WithReconnectBackoff(5*time.Millisecond, 10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.Connect(context.Background(), map[string]interface{}{}); err != nil {
+		t.Fatalf("expected lazy connect to succeed, got %v", err)
+	}
+	defer func() { _ = a.Close() }()
+
+	op := &adapter.Operation{Statement: "SELECT 1"}
+	_, err = a.Fetch(context.Background(), op, nil)
+	if !errors.Is(err, ErrUnavailable) || !errors.Is(err, adapter.ErrConnection) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if a.State() == StateReady {
+		t.Error("expected adapter not to be ready")
+	}
+	select {
+	case <-a.Ready():
+		t.Error("expected ready channel to stay open")
+	default:
+	}
+}
+
+func TestConfig_LazyConnect(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		ConfigLazyConnect:         "true",
+		ConfigReconnectMinBackoff: 0.5,
+		ConfigReconnectMaxBackoff: "1m",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.LazyConnect || cfg.ReconnectMinBackoff != 500*time.Millisecond || cfg.ReconnectMaxBackoff != time.Minute {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	cfg.ReconnectMaxBackoff = 100 * time.Millisecond
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected max backoff below min backoff to be rejected")
+	}
+}
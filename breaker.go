@@ -0,0 +1,252 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// ErrCircuitOpen is returned without contacting the database while the
+// circuit breaker is open. It matches adapter.ErrConnection with errors.Is.
+var ErrCircuitOpen = &adapter.AdapterError{
+	Code:    "CONNECTION",
+	Message: "circuit breaker open",
+	Cause:   adapter.ErrConnection,
+}
+
+// BreakerState is the state of the circuit breaker.
+type BreakerState int
+
+// Circuit breaker states.
+const (
+	// BreakerClosed lets every operation through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails every operation with ErrCircuitOpen.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a limited number of trial operations through to
+	// decide whether to close again.
+	BreakerHalfOpen
+)
+
+// String returns the state name.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig configures the circuit breaker around the connection pool.
+type BreakerConfig struct {
+	// FailureRate is the fraction (0-1] of operations failing with
+	// connection errors within Window that trips the breaker. Zero disables
+	// the breaker.
+	FailureRate float64
+
+	// MinRequests is the number of operations within Window needed before
+	// the failure rate is considered. Defaults to 10.
+	MinRequests int
+
+	// Window is the period over which the failure rate is measured.
+	// Defaults to 10s.
+	Window time.Duration
+
+	// OpenTimeout is how long the breaker stays open before letting trial
+	// operations through. Defaults to 30s.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of trial operations that must succeed
+	// to close the breaker. Defaults to 1.
+	HalfOpenRequests int
+}
+
+// enabled reports whether the breaker is configured.
+func (c BreakerConfig) enabled() bool {
+	return c.FailureRate > 0
+}
+
+// breakerBuckets is the number of buckets the window is divided into.
+const breakerBuckets = 10
+
+// bucket counts operations within a slice of the breaker window.
+type bucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// breaker is a circuit breaker tripping on the rate of connection errors.
+type breaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	state     BreakerState
+	buckets   [breakerBuckets]bucket
+	openedAt  time.Time
+	trials    int
+	successes int
+}
+
+// newBreaker returns a closed breaker, or nil if cfg disables it.
+func newBreaker(cfg BreakerConfig) *breaker {
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &breaker{cfg: cfg, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen if the operation must not run.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trials = 0
+		b.successes = 0
+	}
+	if b.state == BreakerHalfOpen {
+		if b.trials >= b.cfg.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.trials++
+	}
+	return nil
+}
+
+// record counts the outcome of an operation let through by allow.
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	failed := isConnectionError(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.trip()
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.state = BreakerClosed
+			b.buckets = [breakerBuckets]bucket{}
+		}
+
+	case BreakerClosed:
+		total, failures := b.count(failed)
+		if total >= b.cfg.MinRequests && float64(failures) >= b.cfg.FailureRate*float64(total) {
+			b.trip()
+		}
+	}
+}
+
+// count adds an operation to the current bucket and returns the totals
+// over the window.
+func (b *breaker) count(failed bool) (total, failures int) {
+	now := b.now()
+	width := b.cfg.Window / breakerBuckets
+	if width <= 0 {
+		width = 1
+	}
+	start := now.Truncate(width)
+	cur := &b.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !cur.start.Equal(start) {
+		*cur = bucket{start: start}
+	}
+	cur.total++
+	if failed {
+		cur.failures++
+	}
+
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.cfg.Window {
+			total += bk.total
+			failures += bk.failures
+		}
+	}
+	return total, failures
+}
+
+// trip opens the breaker.
+func (b *breaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.buckets = [breakerBuckets]bucket{}
+}
+
+// current returns the breaker state, reporting an open breaker whose
+// timeout has elapsed as half-open.
+func (b *breaker) current() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// BreakerState returns the state of the circuit breaker. It is always
+// closed when no breaker is configured.
+func (a *MySQLAdapter) BreakerState() BreakerState {
+	if a.breaker == nil {
+		return BreakerClosed
+	}
+	return a.breaker.current()
+}
+
+// isConnectionError reports whether err means the server could not be
+// reached or dropped the connection, as opposed to a failed statement.
+func isConnectionError(err error) bool {
+	// context.DeadlineExceeded also satisfies net.Error
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, gomysql.ErrInvalidConn) || errors.Is(err, ErrUnavailable) {
+		return true
+	}
+
+	var mysqlErr *gomysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_CON_COUNT_ERROR and ER_SERVER_SHUTDOWN
+		return mysqlErr.Number == 1040 || mysqlErr.Number == 1053
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
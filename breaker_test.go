@@ -0,0 +1,190 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestBreaker_Transitions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newBreaker(BreakerConfig{FailureRate: 0.5, MinRequests: 4, OpenTimeout: 30 * time.Second, HalfOpenRequests: 2})
+	b.now = func() time.Time { return now }
+
+	// Statement errors do not count as failures
+	for i := 0; i < 4; i++ {
+		b.record(&gomysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	}
+	if b.current() != BreakerClosed {
+		t.Fatalf("expected closed, got %s", b.current())
+	}
+
+	b.record(errRefused)
+	b.record(errRefused)
+	if b.current() != BreakerClosed {
+		t.Fatalf("expected closed below the failure rate, got %s", b.current())
+	}
+
+	// Once the statement errors leave the window the rate trips it
+	now = now.Add(10 * time.Second)
+	b.record(errRefused)
+	b.record(nil)
+	b.record(errRefused)
+	if b.current() != BreakerClosed {
+		t.Fatalf("expected closed below the minimum requests, got %s", b.current())
+	}
+	b.record(errRefused)
+	if b.current() != BreakerOpen {
+		t.Fatalf("expected open, got %s", b.current())
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	// After the timeout a failed trial reopens the breaker
+	now = now.Add(30 * time.Second)
+	if b.current() != BreakerHalfOpen {
+		t.Fatalf("expected half-open, got %s", b.current())
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected trial to be allowed, got %v", err)
+	}
+	b.record(gomysql.ErrInvalidConn)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected breaker to reopen, got %v", err)
+	}
+
+	// Successful trials close it again
+	now = now.Add(30 * time.Second)
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("expected trial %d to be allowed, got %v", i, err)
+		}
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected only two trials, got %v", err)
+	}
+	b.record(nil)
+	b.record(nil)
+	if b.current() != BreakerClosed {
+		t.Errorf("expected closed, got %s", b.current())
+	}
+}
+
+func TestBreaker_Window(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newBreaker(BreakerConfig{FailureRate: 1, MinRequests: 2, Window: 10 * time.Second})
+	b.now = func() time.Time { return now }
+
+	b.record(errRefused)
+	now = now.Add(15 * time.Second)
+	b.record(errRefused)
+	if b.current() != BreakerClosed {
+		t.Error("expected failures outside the window to be forgotten")
+	}
+	now = now.Add(time.Second)
+	b.record(errRefused)
+	if b.current() != BreakerOpen {
+		t.Errorf("expected open, got %s", b.current())
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("mysql: query failed: %w", gomysql.ErrInvalidConn), true},
+		{errRefused, true},
+		{&gomysql.MySQLError{Number: 1040, Message: "Too many connections"}, true},
+		{&gomysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{adapter.ErrNotFound, false},
+		{context.Canceled, false},
+		{fmt.Errorf("mysql: query failed: %w", context.DeadlineExceeded), false},
+	}
+	for _, tt := range tests {
+		if got := isConnectionError(tt.err); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
+
+// countingConnector fails every connection attempt and counts them.
+type countingConnector struct {
+	attempts int
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.attempts++
+	return nil, errRefused
+}
+
+func (c *countingConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestMySQLAdapter_CircuitBreaker(t *testing.T) {
+	dialer := &countingConnector{}
+	a := NewMySQLAdapter()
+	a.db = sql.OpenDB(dialer)
+	defer func() { _ = a.db.Close() }()
+	a.breaker = newBreaker(BreakerConfig{FailureRate: 1, MinRequests: 3})
+
+	op := &adapter.Operation{Statement: "SELECT 1"}
+	for i := 0; i < 3; i++ {
+		if _, err := a.Fetch(context.Background(), op, nil); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("fetch %d: breaker opened too early", i)
+		}
+	}
+	if a.BreakerState() != BreakerOpen {
+		t.Fatalf("expected open breaker, got %s", a.BreakerState())
+	}
+
+	attempts := dialer.attempts
+	if _, err := a.Fetch(context.Background(), op, nil); !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, adapter.ErrConnection) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if err := a.Delete(context.Background(), op, []interface{}{1}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if dialer.attempts != attempts {
+		t.Error("expected no connection attempts while open")
+	}
+}
+
+func TestConfig_Breaker(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.apply(map[string]interface{}{
+		ConfigBreakerFailureRate:      0.5,
+		ConfigBreakerMinRequests:      "20",
+		ConfigBreakerWindow:           30,
+		ConfigBreakerOpenTimeout:      "1m",
+		ConfigBreakerHalfOpenRequests: 3,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := BreakerConfig{FailureRate: 0.5, MinRequests: 20, Window: 30 * time.Second, OpenTimeout: time.Minute, HalfOpenRequests: 3}
+	if cfg.Breaker != want {
+		t.Errorf("expected %+v, got %+v", want, cfg.Breaker)
+	}
+
+	cfg.Breaker.FailureRate = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected failure rate above 1 to be rejected")
+	}
+	if NewMySQLAdapter().BreakerState() != BreakerClosed {
+		t.Error("expected closed state without a breaker")
+	}
+}
@@ -0,0 +1,132 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQLCommentConfig configures sqlcommenter comments appended to every
+// statement, so the slow log and performance_schema show where a statement
+// came from.
+type SQLCommentConfig struct {
+	// Enabled appends comments to statements.
+	Enabled bool
+
+	// Application is the value of the application tag, such as the name of
+	// the service. Empty omits the tag.
+	Application string
+}
+
+// commentTagsKey is the context key of the tags of the running operation.
+type commentTagsKey struct{}
+
+// commentTags describe the operation a statement belongs to.
+type commentTags struct {
+	operation adapter.OperationType
+	mapping   string
+}
+
+// withCommentTags returns ctx carrying the tags of an operation.
+func withCommentTags(ctx context.Context, kind adapter.OperationType, mapping string) context.Context {
+	return context.WithValue(ctx, commentTagsKey{}, commentTags{operation: kind, mapping: mapping})
+}
+
+// commenter appends sqlcommenter comments to statements.
+type commenter struct {
+	application string
+}
+
+// newCommenter returns a commenter for cfg, or nil if comments are disabled.
+func newCommenter(cfg SQLCommentConfig) *commenter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &commenter{application: cfg.Application}
+}
+
+// comment returns query with a comment carrying the application, the
+// operation and mapping in ctx, and the traceparent of the span in ctx.
+// Statements that already end with a comment are left as they are.
+func (c *commenter) comment(ctx context.Context, query string) string {
+	if c == nil || endsWithComment(query) {
+		return query
+	}
+
+	// Tags are sorted by key as the specification requires
+	var tags []string
+	add := func(key, value string) {
+		if value != "" {
+			tags = append(tags, key+"='"+strings.ReplaceAll(url.PathEscape(value), "'", `\'`)+"'")
+		}
+	}
+	t, _ := ctx.Value(commentTagsKey{}).(commentTags)
+	add("application", c.application)
+	add("mapping", t.mapping)
+	add("operation", string(t.operation))
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		add("traceparent", fmt.Sprintf("00-%s-%s-%02x", sc.TraceID(), sc.SpanID(), byte(sc.TraceFlags())))
+	}
+	if len(tags) == 0 {
+		return query
+	}
+
+	// A trailing semicolon would leave the comment as a second statement
+	query = strings.TrimRight(query, "; \t\r\n")
+	return query + " /*" + strings.Join(tags, ",") + "*/"
+}
+
+// endsWithComment reports whether query ends with a comment, ignoring
+// trailing whitespace and semicolons. Comment markers inside quoted strings
+// and identifiers, and optimizer hints, do not count.
+func endsWithComment(query string) bool {
+	trailing := false
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = closingQuote(query, i)
+			trailing = false
+		case c == '#' || strings.HasPrefix(query[i:], "-- ") || strings.HasPrefix(query[i:], "--\t") ||
+			strings.HasPrefix(query[i:], "--\n") || query[i:] == "--":
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return true
+			}
+			i += end
+			trailing = true
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return true
+			}
+			trailing = !strings.HasPrefix(query[i:], "/*+")
+			i += end + 3
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';':
+			// Whitespace keeps a preceding comment trailing
+		default:
+			trailing = false
+		}
+	}
+	return trailing
+}
+
+// closingQuote returns the index of the quote ending the string or
+// identifier opened at query[start], or the last index if it is unterminated.
+func closingQuote(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return len(query) - 1
+}
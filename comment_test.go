@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testSpanContext returns ctx carrying a sampled span context.
+func testSpanContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+func TestCommenter(t *testing.T) {
+	c := newCommenter(SQLCommentConfig{Enabled: true, Application: "billing api's"})
+	ctx := withCommentTags(testSpanContext(context.Background()), adapter.OpFetch, "users")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"SELECT * FROM users WHERE id = ?;",
+			"SELECT * FROM users WHERE id = ? /*application='billing%20api%27s',mapping='users',operation='fetch'," +
+				"traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/",
+		},
+		{"SELECT * FROM users /* report */", "SELECT * FROM users /* report */"},
+		{"SELECT * FROM users -- report\n", "SELECT * FROM users -- report\n"},
+		{
+			"SELECT * FROM users WHERE note = '-- /* x */' AND name = 'it''s' -- id\nAND id = ?",
+			"SELECT * FROM users WHERE note = '-- /* x */' AND name = 'it''s' -- id\nAND id = ? /*application='billing%20api%27s',mapping='users',operation='fetch'," +
+				"traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/",
+		},
+		{
+			"SELECT /*+ MAX_EXECUTION_TIME(100) */ * FROM users",
+			"SELECT /*+ MAX_EXECUTION_TIME(100) */ * FROM users /*application='billing%20api%27s',mapping='users',operation='fetch'," +
+				"traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/",
+		},
+	}
+	for _, tt := range tests {
+		if got := c.comment(ctx, tt.query); got != tt.want {
+			t.Errorf("comment(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	// Statements outside operations carry the application only
+	if got := c.comment(context.Background(), "SELECT 1"); got != "SELECT 1 /*application='billing%20api%27s'*/" {
+		t.Errorf("unexpected comment %q", got)
+	}
+	if got := newCommenter(SQLCommentConfig{}).comment(ctx, "SELECT 1"); got != "SELECT 1" {
+		t.Errorf("expected disabled commenter to leave the statement, got %q", got)
+	}
+}
+
+func TestMySQLAdapter_SQLComments(t *testing.T) {
+	sent := "DELETE FROM users WHERE id = ? /*application='billing',mapping='users',operation='delete'," +
+		"traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/"
+	script := &scriptConnector{results: map[string]fakeResult{sent: {}}}
+	var calls []string
+
+	a, err := NewMySQLAdapterWithOptions(WithSQLComments("billing"), WithInterceptors(func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+		calls = append(calls, call.SQL)
+		return next(ctx, call)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(&connector{base: script, comments: newCommenter(a.config.SQLComments)})
+	defer func() { _ = a.db.Close() }()
+
+	op := &adapter.Operation{Statement: "users", Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}}}
+	if err := a.Delete(testSpanContext(context.Background()), op, []interface{}{1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := script.executed(); len(got) != 1 || got[0] != sent {
+		t.Errorf("expected commented statement, got %v", got)
+	}
+	// The adapter's own view of the statement has no comment
+	if len(calls) != 1 || calls[0] != "DELETE FROM users WHERE id = ?" {
+		t.Errorf("expected interceptors to see the plain statement, got %v", calls)
+	}
+}
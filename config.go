@@ -0,0 +1,1240 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config keys for MySQL adapter configuration
+const (
+	ConfigHost     = "host"
+	ConfigPort     = "port"
+	ConfigUser     = "user"
+	ConfigPassword = "password"
+	ConfigDatabase = "database"
+	ConfigSSL      = "ssl"
+	ConfigMaxConn  = "max_connections"
+	ConfigMaxIdle  = "max_idle"
+	ConfigConnAge  = "conn_max_age_seconds"
+
+	ConfigConnAgeJitter = "conn_max_age_jitter_seconds"
+	ConfigConnIdleTime  = "conn_max_idle_seconds"
+	ConfigDialTimeout   = "dial_timeout_seconds"
+	ConfigReadTimeout   = "read_timeout_seconds"
+	ConfigWriteTimeout  = "write_timeout_seconds"
+
+	ConfigTLSCAFile     = "tls_ca_file"
+	ConfigTLSCertFile   = "tls_cert_file"
+	ConfigTLSKeyFile    = "tls_key_file"
+	ConfigTLSServerName = "tls_server_name"
+	ConfigTLSMinVersion = "tls_min_version"
+	ConfigTLSVerify     = "tls_verify"
+
+	ConfigHosts  = "hosts"
+	ConfigDSN    = "dsn"
+	ConfigSocket = "socket"
+	ConfigParams = "params"
+
+	ConfigSnowflakeNode = "snowflake_node_id"
+
+	ConfigPasswordEnv     = "password_env"
+	ConfigPasswordFile    = "password_file"
+	ConfigPasswordCommand = "password_command"
+	ConfigCredentialsTTL  = "credentials_ttl_seconds"
+
+	ConfigSessionInit = "session_init"
+
+	ConfigLazyConnect         = "lazy_connect"
+	ConfigReconnectMinBackoff = "reconnect_min_backoff_seconds"
+	ConfigReconnectMaxBackoff = "reconnect_max_backoff_seconds"
+
+	ConfigReplicas        = "replicas"
+	ConfigReplicaBalance  = "replica_balance"
+	ConfigReadOnlyActions = "read_only_actions"
+
+	ConfigMaxReplicaLag        = "max_replica_lag_seconds"
+	ConfigReplicaCheckInterval = "replica_check_interval_seconds"
+	ConfigGTIDWaitTimeout      = "gtid_wait_timeout_seconds"
+
+	ConfigBreakerFailureRate      = "breaker_failure_rate"
+	ConfigBreakerMinRequests      = "breaker_min_requests"
+	ConfigBreakerWindow           = "breaker_window_seconds"
+	ConfigBreakerOpenTimeout      = "breaker_open_seconds"
+	ConfigBreakerHalfOpenRequests = "breaker_half_open_requests"
+
+	ConfigLogLevel       = "log_level"
+	ConfigLogErrorLevel  = "log_error_level"
+	ConfigLogShowColumns = "log_show_columns"
+
+	ConfigSlowQuery       = "slow_query_seconds"
+	ConfigSlowExplainRate = "slow_query_explain_rate"
+
+	ConfigSQLComments   = "sql_comments"
+	ConfigSQLCommentApp = "sql_comment_application"
+
+	ConfigCaptureWarnings   = "capture_warnings"
+	ConfigWarningErrorCodes = "warning_error_codes"
+
+	ConfigQueryTimeout  = "query_timeout_seconds"
+	ConfigQueryTimeouts = "query_timeouts"
+	ConfigKillOnCancel  = "kill_on_cancel"
+
+	ConfigRefetchGenerated = "refetch_generated"
+)
+
+// Config holds the typed configuration of a MySQL adapter.
+type Config struct {
+	// Host is the MySQL server hostname.
+	Host string
+
+	// Hosts lists candidate primaries as "host[:port]". When set, the
+	// adapter connects to the one that is writable and fails over to
+	// another when it is demoted. Port defaults to Port.
+	Hosts []string
+
+	// OnFailover is called after the adapter switches to another of Hosts.
+	OnFailover FailoverFunc
+
+	// Port is the MySQL server port.
+	Port int
+
+	// User is the database user.
+	User string
+
+	// Password is the database password.
+	Password string
+
+	// Database is the default database name.
+	Database string
+
+	// SSL is the driver TLS mode (true, false, skip-verify, preferred).
+	SSL string
+
+	// TLS is a custom TLS setup with private CAs and client certificates.
+	// When set it takes precedence over SSL.
+	TLS TLSConfig
+
+	// DSN is a complete driver DSN or mysql:// URL. When set, it replaces
+	// Host, Port, User, Password, Database, Socket and SSL.
+	DSN string
+
+	// Socket is the path of a unix socket to connect through instead of TCP.
+	Socket string
+
+	// Params holds extra driver parameters (collation, loc, timeout,
+	// compress, ...) or session variables appended to the DSN.
+	Params map[string]string
+
+	// SessionInit lists statements run on every new connection before it
+	// is used, such as SET SESSION sql_mode or SET NAMES.
+	SessionInit []string
+
+	// MaxConnections is the maximum number of open connections (0 is unlimited).
+	MaxConnections int
+
+	// MaxIdle is the maximum number of idle connections.
+	MaxIdle int
+
+	// ConnMaxAge is the maximum lifetime of a connection (0 is unlimited).
+	ConnMaxAge time.Duration
+
+	// ConnMaxAgeJitter shortens each connection's lifetime by a random
+	// amount up to this value so connections are not recycled together.
+	ConnMaxAgeJitter time.Duration
+
+	// ConnMaxIdleTime is how long a connection may sit idle before it is
+	// closed (0 is unlimited).
+	ConnMaxIdleTime time.Duration
+
+	// DialTimeout bounds establishing a new connection (0 uses the OS default).
+	DialTimeout time.Duration
+
+	// ReadTimeout bounds each network read (0 is unlimited).
+	ReadTimeout time.Duration
+
+	// WriteTimeout bounds each network write (0 is unlimited).
+	WriteTimeout time.Duration
+
+	// Replicas are read replicas serving Fetch and read-only actions.
+	Replicas []ReplicaConfig
+
+	// ReplicaBalance is how reads are spread across replicas:
+	// round_robin (default) or least_connections.
+	ReplicaBalance string
+
+	// ReadOnlyActions names actions that may run on a replica in addition
+	// to plain SELECT statements, such as read-only stored procedures.
+	ReadOnlyActions []string
+
+	// MaxReplicaLag excludes replicas whose Seconds_Behind_Source exceeds
+	// it, or whose lag cannot be read (0 disables lag checks).
+	MaxReplicaLag time.Duration
+
+	// ReplicaCheckInterval is how often replica lag is checked. Defaults to 5s.
+	ReplicaCheckInterval time.Duration
+
+	// GTIDWaitTimeout is how long a read in a WithReadYourWrites context
+	// waits for a replica to apply the context's writes before falling back
+	// to the primary (0 reads from the primary straight away).
+	GTIDWaitTimeout time.Duration
+
+	// LazyConnect makes Connect return without reaching the database.
+	// The adapter connects in the background and operations fail with
+	// ErrUnavailable until the database is reachable.
+	LazyConnect bool
+
+	// ReconnectMinBackoff and ReconnectMaxBackoff bound the exponential
+	// backoff between background connection attempts in lazy mode. Zero
+	// values use 1s and 30s.
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
+
+	// OnStateChange is called when a lazily connected adapter's
+	// availability changes.
+	OnStateChange StateFunc
+
+	// Interceptors wrap every statement run by an operation, the first
+	// being the outermost.
+	Interceptors []Interceptor
+
+	// TracerProvider, when set, records OpenTelemetry spans for every
+	// adapter operation and statement.
+	TracerProvider trace.TracerProvider
+
+	// Metrics receives operation and pool measurements. Nil discards them.
+	Metrics Metrics
+
+	// Log configures logging of every statement run by an operation, with
+	// parameter values redacted unless shown or masked per column.
+	Log LogConfig
+
+	// SlowQuery reports fetches and actions exceeding a threshold, with a
+	// sample of their plans.
+	SlowQuery SlowQueryConfig
+
+	// SQLComments appends sqlcommenter comments naming the application,
+	// mapping, operation and trace to every statement.
+	SQLComments SQLCommentConfig
+
+	// Warnings reads the warnings of writes, returning them or failing the
+	// write on configured codes.
+	Warnings WarningsConfig
+
+	// QueryTimeout bounds every operation (0 leaves operations to their
+	// context). SELECT statements also carry it as a MAX_EXECUTION_TIME
+	// hint so the server stops them on its own.
+	QueryTimeout time.Duration
+
+	// QueryTimeouts overrides QueryTimeout per mapping, keyed by the table
+	// of a fetch, insert, update or delete, or the name of an action.
+	QueryTimeouts map[string]time.Duration
+
+	// KillOnCancel runs KILL QUERY from a separate connection when an
+	// operation's context ends while a statement runs, instead of leaving it
+	// running on the server.
+	KillOnCancel bool
+
+	// RefetchGenerated re-selects the server-generated columns of a
+	// mapping, other than its identifiers, after single inserts and updates
+	// and writes them back into the object.
+	RefetchGenerated bool
+
+	// Breaker configures the circuit breaker failing operations fast with
+	// ErrCircuitOpen while connection errors persist.
+	Breaker BreakerConfig
+
+	// SnowflakeNodeID is the node ID of the built-in snowflake generator.
+	SnowflakeNodeID int64
+
+	// Credentials supplies the user and password for every new connection,
+	// overriding User and Password. It takes precedence over PasswordEnv,
+	// PasswordFile and PasswordCommand.
+	Credentials CredentialProvider
+
+	// PasswordEnv names an environment variable holding the password.
+	PasswordEnv string
+
+	// PasswordFile is a file holding the password, re-read for every new
+	// connection.
+	PasswordFile string
+
+	// PasswordCommand is a command whose output is the password, such as a
+	// cloud IAM token generator.
+	PasswordCommand []string
+
+	// CredentialsTTL caches credentials for this long instead of looking
+	// them up for every new connection (0 disables caching).
+	CredentialsTTL time.Duration
+}
+
+// DefaultConfig returns the configuration used when no settings are given.
+func DefaultConfig() Config {
+	return Config{
+		Host:           "localhost",
+		Port:           3306,
+		User:           "root",
+		SSL:            "false",
+		MaxConnections: 10,
+		MaxIdle:        5,
+		ConnMaxAge:     time.Hour,
+
+		ReplicaBalance: BalanceRoundRobin,
+
+		ReconnectMinBackoff: time.Second,
+		ReconnectMaxBackoff: 30 * time.Second,
+	}
+}
+
+// ConfigError lists every problem found in an adapter configuration.
+// It matches adapter.ErrConfiguration with errors.Is.
+type ConfigError struct {
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	return "mysql: invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Unwrap allows errors.Is(err, adapter.ErrConfiguration) to match.
+func (e *ConfigError) Unwrap() error {
+	return adapter.ErrConfiguration
+}
+
+// Validate checks the configuration and reports all problems at once.
+func (c Config) Validate() error {
+	var problems []string
+
+	// Connection settings are only used without a raw DSN
+	if c.DSN == "" {
+		if c.Host == "" && c.Socket == "" && len(c.Hosts) == 0 {
+			problems = append(problems, "host, hosts or socket must be set")
+		}
+		for i, h := range c.Hosts {
+			if _, err := hostAddr(h, c.Port); err != nil {
+				problems = append(problems, fmt.Sprintf("hosts[%d]: %v", i, err))
+			}
+		}
+		if c.Socket == "" && (c.Port < 1 || c.Port > 65535) {
+			problems = append(problems, fmt.Sprintf("port must be between 1 and 65535, got %d", c.Port))
+		}
+		if c.User == "" {
+			problems = append(problems, "user must not be empty")
+		}
+	}
+	switch c.SSL {
+	case "true", "false", "skip-verify", "preferred":
+	default:
+		problems = append(problems, fmt.Sprintf("ssl must be one of true, false, skip-verify, preferred, got %q", c.SSL))
+	}
+	if c.TLS.enabled() {
+		if _, err := c.TLS.build(c.Host); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if c.MaxConnections < 0 {
+		problems = append(problems, fmt.Sprintf("max_connections must not be negative, got %d", c.MaxConnections))
+	}
+	if c.MaxIdle < 0 {
+		problems = append(problems, fmt.Sprintf("max_idle must not be negative, got %d", c.MaxIdle))
+	}
+	if c.MaxConnections > 0 && c.MaxIdle > c.MaxConnections {
+		problems = append(problems, fmt.Sprintf("max_idle (%d) must not exceed max_connections (%d)", c.MaxIdle, c.MaxConnections))
+	}
+	if c.ConnMaxAge < 0 {
+		problems = append(problems, fmt.Sprintf("conn_max_age_seconds must not be negative, got %s", c.ConnMaxAge))
+	}
+	if c.ConnMaxAgeJitter < 0 || (c.ConnMaxAge > 0 && c.ConnMaxAgeJitter >= c.ConnMaxAge) {
+		problems = append(problems, fmt.Sprintf("conn_max_age_jitter_seconds must be between 0 and conn_max_age_seconds, got %s", c.ConnMaxAgeJitter))
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{ConfigConnIdleTime, c.ConnMaxIdleTime},
+		{ConfigDialTimeout, c.DialTimeout},
+		{ConfigReadTimeout, c.ReadTimeout},
+		{ConfigWriteTimeout, c.WriteTimeout},
+		{ConfigMaxReplicaLag, c.MaxReplicaLag},
+		{ConfigReplicaCheckInterval, c.ReplicaCheckInterval},
+		{ConfigGTIDWaitTimeout, c.GTIDWaitTimeout},
+		{ConfigReconnectMinBackoff, c.ReconnectMinBackoff},
+		{ConfigReconnectMaxBackoff, c.ReconnectMaxBackoff},
+		{ConfigBreakerWindow, c.Breaker.Window},
+		{ConfigBreakerOpenTimeout, c.Breaker.OpenTimeout},
+		{ConfigSlowQuery, c.SlowQuery.Threshold},
+		{ConfigQueryTimeout, c.QueryTimeout},
+	} {
+		if d.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative, got %s", d.key, d.value))
+		}
+	}
+	switch c.ReplicaBalance {
+	case "", BalanceRoundRobin, BalanceLeastConnections:
+	default:
+		problems = append(problems, fmt.Sprintf("replica_balance must be one of %s, %s, got %q", BalanceRoundRobin, BalanceLeastConnections, c.ReplicaBalance))
+	}
+	for i, r := range c.Replicas {
+		if r.Host == "" && r.Socket == "" && r.DSN == "" {
+			problems = append(problems, fmt.Sprintf("replicas[%d] must set host, socket or dsn", i))
+		}
+		if r.Port < 0 || r.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("replicas[%d].port must be between 1 and 65535, got %d", i, r.Port))
+		}
+	}
+	if c.ReconnectMaxBackoff > 0 && c.ReconnectMaxBackoff < c.ReconnectMinBackoff {
+		problems = append(problems, fmt.Sprintf("reconnect_max_backoff_seconds (%s) must not be below reconnect_min_backoff_seconds (%s)", c.ReconnectMaxBackoff, c.ReconnectMinBackoff))
+	}
+	if c.Breaker.FailureRate < 0 || c.Breaker.FailureRate > 1 {
+		problems = append(problems, fmt.Sprintf("breaker_failure_rate must be between 0 and 1, got %g", c.Breaker.FailureRate))
+	}
+	if c.Breaker.MinRequests < 0 {
+		problems = append(problems, fmt.Sprintf("breaker_min_requests must not be negative, got %d", c.Breaker.MinRequests))
+	}
+	if c.Breaker.HalfOpenRequests < 0 {
+		problems = append(problems, fmt.Sprintf("breaker_half_open_requests must not be negative, got %d", c.Breaker.HalfOpenRequests))
+	}
+	for mapping, d := range c.QueryTimeouts {
+		if d < 0 {
+			problems = append(problems, fmt.Sprintf("%s.%s must not be negative, got %s", ConfigQueryTimeouts, mapping, d))
+		}
+	}
+	if c.SlowQuery.ExplainRate < 0 || c.SlowQuery.ExplainRate > 1 {
+		problems = append(problems, fmt.Sprintf("slow_query_explain_rate must be between 0 and 1, got %g", c.SlowQuery.ExplainRate))
+	}
+	sources := 0
+	for _, set := range []bool{c.PasswordEnv != "", c.PasswordFile != "", len(c.PasswordCommand) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		problems = append(problems, "only one of password_env, password_file and password_command may be set")
+	}
+	if c.CredentialsTTL < 0 {
+		problems = append(problems, fmt.Sprintf("credentials_ttl_seconds must not be negative, got %s", c.CredentialsTTL))
+	}
+	if c.SnowflakeNodeID < 0 || c.SnowflakeNodeID > MaxSnowflakeNode {
+		problems = append(problems, fmt.Sprintf("snowflake_node_id must be between 0 and %d, got %d", MaxSnowflakeNode, c.SnowflakeNodeID))
+	}
+
+	// Let the driver check the DSN and parameters once the settings are sane
+	if len(problems) == 0 {
+		if _, err := c.driverConfig(); err != nil {
+			problems = append(problems, strings.TrimPrefix(err.Error(), "mysql: "))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// hostAddrs returns the addresses of the candidate primaries.
+func (c Config) hostAddrs() []string {
+	addrs := make([]string, 0, len(c.Hosts))
+	for _, h := range c.Hosts {
+		if addr, err := hostAddr(h, c.Port); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// hostAddr returns the "host:port" address of a "host[:port]" entry.
+func hostAddr(host string, defaultPort int) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("host must not be empty")
+	}
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return net.JoinHostPort(host, strconv.Itoa(defaultPort)), nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid port in %q", host)
+	}
+	return net.JoinHostPort(h, port), nil
+}
+
+// credentialProvider returns the provider consulted for new connections,
+// or nil when the static User and Password are used.
+func (c Config) credentialProvider() CredentialProvider {
+	var p CredentialProvider
+	switch {
+	case c.Credentials != nil:
+		p = c.Credentials
+	case c.PasswordEnv != "":
+		p = EnvCredentials{PasswordVar: c.PasswordEnv}
+	case c.PasswordFile != "":
+		p = FileCredentials{PasswordFile: c.PasswordFile}
+	case len(c.PasswordCommand) > 0:
+		p = ExecCredentials{Command: c.PasswordCommand}
+	default:
+		return nil
+	}
+
+	if c.CredentialsTTL > 0 {
+		p = NewCachedCredentials(p, c.CredentialsTTL)
+	}
+	return p
+}
+
+// Option configures a MySQL adapter created with NewMySQLAdapterWithOptions.
+type Option func(*Config)
+
+// WithHost sets the server hostname.
+func WithHost(host string) Option {
+	return func(c *Config) { c.Host = host }
+}
+
+// WithPort sets the server port.
+func WithPort(port int) Option {
+	return func(c *Config) { c.Port = port }
+}
+
+// WithCredentials sets the database user and password.
+func WithCredentials(user, password string) Option {
+	return func(c *Config) {
+		c.User = user
+		c.Password = password
+	}
+}
+
+// WithDatabase sets the default database name.
+func WithDatabase(database string) Option {
+	return func(c *Config) { c.Database = database }
+}
+
+// WithSSL sets the driver TLS mode.
+func WithSSL(mode string) Option {
+	return func(c *Config) { c.SSL = mode }
+}
+
+// WithMaxConnections sets the maximum number of open connections.
+func WithMaxConnections(n int) Option {
+	return func(c *Config) { c.MaxConnections = n }
+}
+
+// WithMaxIdle sets the maximum number of idle connections.
+func WithMaxIdle(n int) Option {
+	return func(c *Config) { c.MaxIdle = n }
+}
+
+// WithConnMaxAge sets the maximum lifetime of a connection.
+func WithConnMaxAge(d time.Duration) Option {
+	return func(c *Config) { c.ConnMaxAge = d }
+}
+
+// WithConnMaxAgeJitter spreads connection recycling over up to d.
+func WithConnMaxAgeJitter(d time.Duration) Option {
+	return func(c *Config) { c.ConnMaxAgeJitter = d }
+}
+
+// WithConnMaxIdleTime sets how long a connection may sit idle.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(c *Config) { c.ConnMaxIdleTime = d }
+}
+
+// WithTimeouts sets the dial, read and write timeouts.
+func WithTimeouts(dial, read, write time.Duration) Option {
+	return func(c *Config) {
+		c.DialTimeout = dial
+		c.ReadTimeout = read
+		c.WriteTimeout = write
+	}
+}
+
+// WithSnowflakeNode sets the node ID of the built-in snowflake generator.
+func WithSnowflakeNode(node int64) Option {
+	return func(c *Config) { c.SnowflakeNodeID = node }
+}
+
+// WithCredentialProvider looks up credentials through p for every new
+// connection.
+func WithCredentialProvider(p CredentialProvider) Option {
+	return func(c *Config) { c.Credentials = p }
+}
+
+// WithTLS sets a custom TLS configuration.
+func WithTLS(t TLSConfig) Option {
+	return func(c *Config) { c.TLS = t }
+}
+
+// WithHosts sets candidate primaries to fail over between, calling onEvent
+// (which may be nil) after every switch.
+func WithHosts(onEvent FailoverFunc, hosts ...string) Option {
+	return func(c *Config) {
+		c.Hosts = hosts
+		c.OnFailover = onEvent
+	}
+}
+
+// WithDSN sets a complete driver DSN or mysql:// URL.
+func WithDSN(dsn string) Option {
+	return func(c *Config) { c.DSN = dsn }
+}
+
+// WithSocket connects through a unix socket instead of TCP.
+func WithSocket(path string) Option {
+	return func(c *Config) { c.Socket = path }
+}
+
+// WithParam adds an extra driver parameter or session variable to the DSN.
+func WithParam(key, value string) Option {
+	return func(c *Config) {
+		if c.Params == nil {
+			c.Params = make(map[string]string)
+		}
+		c.Params[key] = value
+	}
+}
+
+// WithSessionInit adds statements run on every new connection.
+func WithSessionInit(statements ...string) Option {
+	return func(c *Config) { c.SessionInit = append(c.SessionInit, statements...) }
+}
+
+// WithReplicas adds read replicas.
+func WithReplicas(replicas ...ReplicaConfig) Option {
+	return func(c *Config) { c.Replicas = append(c.Replicas, replicas...) }
+}
+
+// WithReplicaBalance sets how reads are spread across replicas.
+func WithReplicaBalance(balance string) Option {
+	return func(c *Config) { c.ReplicaBalance = balance }
+}
+
+// WithMaxReplicaLag excludes replicas lagging more than maxLag, checked
+// every interval.
+func WithMaxReplicaLag(maxLag, interval time.Duration) Option {
+	return func(c *Config) {
+		c.MaxReplicaLag = maxLag
+		c.ReplicaCheckInterval = interval
+	}
+}
+
+// WithGTIDWaitTimeout sets how long read-your-writes reads wait for a replica.
+func WithGTIDWaitTimeout(d time.Duration) Option {
+	return func(c *Config) { c.GTIDWaitTimeout = d }
+}
+
+// WithLazyConnect makes Connect return immediately and reach the database in
+// the background, calling onChange (which may be nil) on state transitions.
+func WithLazyConnect(onChange StateFunc) Option {
+	return func(c *Config) {
+		c.LazyConnect = true
+		c.OnStateChange = onChange
+	}
+}
+
+// WithReconnectBackoff sets the backoff bounds between background
+// connection attempts.
+func WithReconnectBackoff(min, max time.Duration) Option {
+	return func(c *Config) {
+		c.ReconnectMinBackoff = min
+		c.ReconnectMaxBackoff = max
+	}
+}
+
+// WithCircuitBreaker enables the circuit breaker around the connection pool.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(c *Config) { c.Breaker = cfg }
+}
+
+// WithInterceptors appends interceptors to the chain wrapping every
+// statement.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Config) { c.Interceptors = append(c.Interceptors, interceptors...) }
+}
+
+// WithTracerProvider enables OpenTelemetry tracing through tp.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Config) { c.TracerProvider = tp }
+}
+
+// WithMetrics reports operation and pool measurements to m.
+func WithMetrics(m Metrics) Option {
+	return func(c *Config) { c.Metrics = m }
+}
+
+// WithLogging logs every statement run by an operation as cfg describes.
+func WithLogging(cfg LogConfig) Option {
+	return func(c *Config) { c.Log = cfg }
+}
+
+// WithSlowQueries reports fetches and actions slower than cfg.Threshold.
+func WithSlowQueries(cfg SlowQueryConfig) Option {
+	return func(c *Config) { c.SlowQuery = cfg }
+}
+
+// WithSQLComments appends sqlcommenter comments to every statement, tagged
+// with application.
+func WithSQLComments(application string) Option {
+	return func(c *Config) { c.SQLComments = SQLCommentConfig{Enabled: true, Application: application} }
+}
+
+// WithWarnings reads the warnings of every write as cfg describes.
+func WithWarnings(cfg WarningsConfig) Option {
+	return func(c *Config) { c.Warnings = cfg }
+}
+
+// WithQueryTimeout bounds every operation by d.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(c *Config) { c.QueryTimeout = d }
+}
+
+// WithMappingTimeout bounds the operations on mapping, a table or action
+// name, by d.
+func WithMappingTimeout(mapping string, d time.Duration) Option {
+	return func(c *Config) {
+		if c.QueryTimeouts == nil {
+			c.QueryTimeouts = make(map[string]time.Duration)
+		}
+		c.QueryTimeouts[mapping] = d
+	}
+}
+
+// WithKillOnCancel kills statements on the server when their operation's
+// context ends.
+func WithKillOnCancel() Option {
+	return func(c *Config) { c.KillOnCancel = true }
+}
+
+// WithRefetchGenerated re-selects server-generated columns after single
+// inserts and updates.
+func WithRefetchGenerated() Option {
+	return func(c *Config) { c.RefetchGenerated = true }
+}
+
+// WithConfig replaces the whole configuration.
+func WithConfig(cfg Config) Option {
+	return func(c *Config) { *c = cfg }
+}
+
+// apply overlays a source configuration map on c. Unknown keys and values
+// of the wrong type are reported together.
+func (c *Config) apply(config map[string]interface{}) error {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		if err := c.set(key, config[key]); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// load applies config and validates the result, reporting the problems of
+// both in a single ConfigError.
+func (c *Config) load(config map[string]interface{}) error {
+	var problems []string
+	for _, err := range []error{c.apply(config), c.Validate()} {
+		var cerr *ConfigError
+		if errors.As(err, &cerr) {
+			problems = append(problems, cerr.Problems...)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// set stores a single configuration value.
+func (c *Config) set(key string, v interface{}) error {
+	var err error
+	var n int
+
+	switch key {
+	case ConfigHost:
+		c.Host, err = stringValue(key, v)
+	case ConfigPort:
+		c.Port, err = intValue(key, v)
+	case ConfigUser:
+		c.User, err = stringValue(key, v)
+	case ConfigPassword:
+		c.Password, err = stringValue(key, v)
+	case ConfigDatabase:
+		c.Database, err = stringValue(key, v)
+	case ConfigSSL:
+		c.SSL, err = boolStringValue(key, v)
+	case ConfigMaxConn:
+		c.MaxConnections, err = intValue(key, v)
+	case ConfigMaxIdle:
+		c.MaxIdle, err = intValue(key, v)
+	case ConfigConnAge:
+		c.ConnMaxAge, err = durationValue(key, v)
+	case ConfigConnAgeJitter:
+		c.ConnMaxAgeJitter, err = durationValue(key, v)
+	case ConfigConnIdleTime:
+		c.ConnMaxIdleTime, err = durationValue(key, v)
+	case ConfigDialTimeout:
+		c.DialTimeout, err = durationValue(key, v)
+	case ConfigReadTimeout:
+		c.ReadTimeout, err = durationValue(key, v)
+	case ConfigWriteTimeout:
+		c.WriteTimeout, err = durationValue(key, v)
+	case ConfigTLSCAFile:
+		c.TLS.CAFile, err = stringValue(key, v)
+	case ConfigTLSCertFile:
+		c.TLS.CertFile, err = stringValue(key, v)
+	case ConfigTLSKeyFile:
+		c.TLS.KeyFile, err = stringValue(key, v)
+	case ConfigTLSServerName:
+		c.TLS.ServerName, err = stringValue(key, v)
+	case ConfigTLSMinVersion:
+		c.TLS.MinVersion, err = versionValue(key, v)
+	case ConfigTLSVerify:
+		c.TLS.Verify, err = stringValue(key, v)
+	case ConfigHosts:
+		c.Hosts, err = stringsValue(key, v)
+	case ConfigDSN:
+		c.DSN, err = stringValue(key, v)
+	case ConfigSocket:
+		c.Socket, err = stringValue(key, v)
+	case ConfigParams:
+		c.Params, err = paramsValue(key, v)
+	case ConfigSnowflakeNode:
+		n, err = intValue(key, v)
+		c.SnowflakeNodeID = int64(n)
+	case ConfigPasswordEnv:
+		c.PasswordEnv, err = stringValue(key, v)
+	case ConfigPasswordFile:
+		c.PasswordFile, err = stringValue(key, v)
+	case ConfigPasswordCommand:
+		c.PasswordCommand, err = commandValue(key, v)
+	case ConfigCredentialsTTL:
+		c.CredentialsTTL, err = durationValue(key, v)
+	case ConfigSessionInit:
+		c.SessionInit, err = sessionInitValue(key, v)
+	case ConfigReplicas:
+		c.Replicas, err = replicasValue(key, v)
+	case ConfigReplicaBalance:
+		c.ReplicaBalance, err = stringValue(key, v)
+	case ConfigReadOnlyActions:
+		c.ReadOnlyActions, err = stringsValue(key, v)
+	case ConfigMaxReplicaLag:
+		c.MaxReplicaLag, err = durationValue(key, v)
+	case ConfigReplicaCheckInterval:
+		c.ReplicaCheckInterval, err = durationValue(key, v)
+	case ConfigGTIDWaitTimeout:
+		c.GTIDWaitTimeout, err = durationValue(key, v)
+	case ConfigLazyConnect:
+		c.LazyConnect, err = boolValue(key, v)
+	case ConfigReconnectMinBackoff:
+		c.ReconnectMinBackoff, err = durationValue(key, v)
+	case ConfigReconnectMaxBackoff:
+		c.ReconnectMaxBackoff, err = durationValue(key, v)
+	case ConfigBreakerFailureRate:
+		c.Breaker.FailureRate, err = floatValue(key, v)
+	case ConfigBreakerMinRequests:
+		c.Breaker.MinRequests, err = intValue(key, v)
+	case ConfigBreakerWindow:
+		c.Breaker.Window, err = durationValue(key, v)
+	case ConfigBreakerOpenTimeout:
+		c.Breaker.OpenTimeout, err = durationValue(key, v)
+	case ConfigBreakerHalfOpenRequests:
+		c.Breaker.HalfOpenRequests, err = intValue(key, v)
+	case ConfigLogLevel:
+		c.Log.Level, err = levelValue(key, v)
+	case ConfigLogErrorLevel:
+		c.Log.ErrorLevel, err = levelValue(key, v)
+	case ConfigLogShowColumns:
+		c.Log.ShowColumns, err = stringsValue(key, v)
+	case ConfigSlowQuery:
+		c.SlowQuery.Threshold, err = durationValue(key, v)
+	case ConfigSlowExplainRate:
+		c.SlowQuery.ExplainRate, err = floatValue(key, v)
+	case ConfigSQLComments:
+		c.SQLComments.Enabled, err = boolValue(key, v)
+	case ConfigSQLCommentApp:
+		c.SQLComments.Application, err = stringValue(key, v)
+	case ConfigCaptureWarnings:
+		c.Warnings.Capture, err = boolValue(key, v)
+	case ConfigWarningErrorCodes:
+		c.Warnings.ErrorCodes, err = intsValue(key, v)
+	case ConfigQueryTimeout:
+		c.QueryTimeout, err = durationValue(key, v)
+	case ConfigQueryTimeouts:
+		c.QueryTimeouts, err = durationsValue(key, v)
+	case ConfigKillOnCancel:
+		c.KillOnCancel, err = boolValue(key, v)
+	case ConfigRefetchGenerated:
+		c.RefetchGenerated, err = boolValue(key, v)
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+
+	return err
+}
+
+// stringValue converts a config value to a string.
+func stringValue(key string, v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("%s must be a string, got %T", key, v)
+}
+
+// boolValue converts a config value to a bool. Strings such as "true" from
+// environment substitutions are accepted.
+func boolValue(key string, v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(b)); err == nil {
+			return parsed, nil
+		}
+		return false, fmt.Errorf("%s must be a boolean, got %q", key, b)
+	}
+	return false, fmt.Errorf("%s must be a boolean, got %T(%v)", key, v, v)
+}
+
+// boolStringValue converts a config value that may be written as a YAML
+// boolean or a string to a string.
+func boolStringValue(key string, v interface{}) (string, error) {
+	if b, ok := v.(bool); ok {
+		return strconv.FormatBool(b), nil
+	}
+	return stringValue(key, v)
+}
+
+// intValue converts a config value to an int. Whole floats (JSON numbers)
+// and numeric strings (environment substitutions) are accepted.
+func intValue(key string, v interface{}) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case uint64:
+		if n <= math.MaxInt {
+			return int(n), nil
+		}
+	case float64:
+		if n == math.Trunc(n) {
+			return int(n), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(n)); err == nil {
+			return i, nil
+		}
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, n)
+	}
+	return 0, fmt.Errorf("%s must be an integer, got %T(%v)", key, v, v)
+}
+
+// floatValue converts a config value to a float.
+func floatValue(key string, v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil {
+			return f, nil
+		}
+		return 0, fmt.Errorf("%s must be a number, got %q", key, n)
+	}
+	return 0, fmt.Errorf("%s must be a number, got %T(%v)", key, v, v)
+}
+
+// versionValue converts a version number that YAML may decode as a float
+// (tls_min_version: 1.2) to a string.
+func versionValue(key string, v interface{}) (string, error) {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', 1, 64), nil
+	}
+	return stringValue(key, v)
+}
+
+// durationValue converts a config value given in seconds to a duration.
+// Fractional seconds and Go duration strings ("500ms") are accepted.
+func durationValue(key string, v interface{}) (time.Duration, error) {
+	switch n := v.(type) {
+	case int:
+		return time.Duration(n) * time.Second, nil
+	case int64:
+		return time.Duration(n) * time.Second, nil
+	case float64:
+		return time.Duration(n * float64(time.Second)), nil
+	case string:
+		if d, err := time.ParseDuration(strings.TrimSpace(n)); err == nil {
+			return d, nil
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil {
+			return time.Duration(f * float64(time.Second)), nil
+		}
+		return 0, fmt.Errorf("%s must be a number of seconds or a duration, got %q", key, n)
+	}
+	return 0, fmt.Errorf("%s must be a number of seconds or a duration, got %T(%v)", key, v, v)
+}
+
+// commandValue converts a config value to a command line. A list is used as
+// the program and its arguments; a string is split on whitespace.
+func commandValue(key string, v interface{}) ([]string, error) {
+	switch c := v.(type) {
+	case string:
+		return strings.Fields(c), nil
+	case []string:
+		return c, nil
+	case []interface{}:
+		args := make([]string, len(c))
+		for i, arg := range c {
+			s, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d] must be a string, got %T", key, i, arg)
+			}
+			args[i] = s
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("%s must be a string or a list of strings, got %T", key, v)
+}
+
+// stringsValue converts a config value to a list of strings.
+func stringsValue(key string, v interface{}) ([]string, error) {
+	switch l := v.(type) {
+	case []string:
+		return l, nil
+	case []interface{}:
+		values := make([]string, len(l))
+		for i, item := range l {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d] must be a string, got %T", key, i, item)
+			}
+			values[i] = s
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s must be a list of strings, got %T", key, v)
+}
+
+// intsValue converts a config value to a list of integers.
+func intsValue(key string, v interface{}) ([]int, error) {
+	switch l := v.(type) {
+	case []int:
+		return l, nil
+	case []interface{}:
+		values := make([]int, len(l))
+		for i, item := range l {
+			n, err := intValue(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = n
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s must be a list of integers, got %T", key, v)
+}
+
+// levelValue converts a config value to a log level such as "debug" or
+// "warn+2".
+func levelValue(key string, v interface{}) (slog.Leveler, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string, got %T", key, v)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return nil, fmt.Errorf("%s must be a log level such as debug, info, warn or error, got %q", key, s)
+	}
+	return level, nil
+}
+
+// replicasValue converts a config value to replica endpoints. Each entry is
+// a "host[:port]" string, a mysql:// URL, or a map with host, port, socket
+// or dsn keys.
+func replicasValue(key string, v interface{}) ([]ReplicaConfig, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list, got %T", key, v)
+	}
+
+	replicas := make([]ReplicaConfig, len(list))
+	for i, item := range list {
+		entry := fmt.Sprintf("%s[%d]", key, i)
+		switch r := item.(type) {
+		case string:
+			replica, err := replicaAddress(entry, r)
+			if err != nil {
+				return nil, err
+			}
+			replicas[i] = replica
+		case map[string]interface{}:
+			for name, value := range r {
+				var err error
+				switch name {
+				case ConfigHost:
+					replicas[i].Host, err = stringValue(entry+"."+name, value)
+				case ConfigPort:
+					replicas[i].Port, err = intValue(entry+"."+name, value)
+				case ConfigSocket:
+					replicas[i].Socket, err = stringValue(entry+"."+name, value)
+				case ConfigDSN:
+					replicas[i].DSN, err = stringValue(entry+"."+name, value)
+				default:
+					err = fmt.Errorf("%s: unknown key %q", entry, name)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s must be a string or a map, got %T", entry, item)
+		}
+	}
+	return replicas, nil
+}
+
+// replicaAddress parses a replica given as a string.
+func replicaAddress(key, addr string) (ReplicaConfig, error) {
+	if strings.HasPrefix(addr, URLScheme) {
+		return ReplicaConfig{DSN: addr}, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ReplicaConfig{Host: addr}, nil
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return ReplicaConfig{}, fmt.Errorf("%s: invalid port in %q", key, addr)
+	}
+	return ReplicaConfig{Host: host, Port: n}, nil
+}
+
+// sessionInitValue converts a config value to session initialization
+// statements. A list is taken as statements; a map of session variables
+// becomes one SET SESSION statement in key order.
+func sessionInitValue(key string, v interface{}) ([]string, error) {
+	switch m := v.(type) {
+	case []string:
+		return m, nil
+	case []interface{}:
+		statements := make([]string, len(m))
+		for i, stmt := range m {
+			s, ok := stmt.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d] must be a string, got %T", key, i, stmt)
+			}
+			statements[i] = s
+		}
+		return statements, nil
+	case map[string]interface{}:
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		assignments := make([]string, len(names))
+		for i, name := range names {
+			if !isSessionVariable(name) {
+				return nil, fmt.Errorf("%s: invalid session variable name %q", key, name)
+			}
+			value, err := sessionValue(m[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s %w", key, name, err)
+			}
+			assignments[i] = name + " = " + value
+		}
+		return []string{"SET SESSION " + strings.Join(assignments, ", ")}, nil
+	}
+	return nil, fmt.Errorf("%s must be a list of statements or a map of session variables, got %T", key, v)
+}
+
+// isSessionVariable reports whether name is a plain system variable name.
+func isSessionVariable(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionValue formats a session variable value as a SQL literal.
+func sessionValue(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(n) + "'", nil
+	case bool:
+		if n {
+			return "ON", nil
+		}
+		return "OFF", nil
+	case int, int64, uint64, float64:
+		return fmt.Sprint(n), nil
+	}
+	return "", fmt.Errorf("must be a scalar, got %T", v)
+}
+
+// durationsValue converts a config value to durations by name.
+func durationsValue(key string, v interface{}) (map[string]time.Duration, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a map, got %T", key, v)
+	}
+	durations := make(map[string]time.Duration, len(m))
+	for name, value := range m {
+		d, err := durationValue(key+"."+name, value)
+		if err != nil {
+			return nil, err
+		}
+		durations[name] = d
+	}
+	return durations, nil
+}
+
+// paramsValue converts a config value to a map of DSN parameters. Scalar
+// values are formatted as strings.
+func paramsValue(key string, v interface{}) (map[string]string, error) {
+	switch m := v.(type) {
+	case map[string]string:
+		return m, nil
+	case map[string]interface{}:
+		params := make(map[string]string, len(m))
+		for name, value := range m {
+			switch value.(type) {
+			case string, bool, int, int64, float64:
+				params[name] = fmt.Sprint(value)
+			default:
+				return nil, fmt.Errorf("%s.%s must be a scalar, got %T", key, name, value)
+			}
+		}
+		return params, nil
+	}
+	return nil, fmt.Errorf("%s must be a map, got %T", key, v)
+}
@@ -0,0 +1,246 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("expected default config to be valid, got %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Host = ""
+	cfg.Port = 0
+	cfg.SSL = "maybe"
+	cfg.MaxIdle = -1
+
+	err := cfg.Validate()
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	if len(cerr.Problems) != 4 {
+		t.Errorf("expected 4 problems, got %d: %v", len(cerr.Problems), cerr.Problems)
+	}
+	if !errors.Is(err, adapter.ErrConfiguration) {
+		t.Error("expected error to match adapter.ErrConfiguration")
+	}
+}
+
+func TestConfig_Apply(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"host":                 "db.internal",
+		"port":                 float64(3307),
+		"ssl":                  true,
+		"max_connections":      "25",
+		"conn_max_age_seconds": 60,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Host != "db.internal" || cfg.Port != 3307 || cfg.SSL != "true" {
+		t.Errorf("unexpected connection settings %+v", cfg)
+	}
+	if cfg.MaxConnections != 25 {
+		t.Errorf("expected max_connections 25, got %d", cfg.MaxConnections)
+	}
+	if cfg.ConnMaxAge != time.Minute {
+		t.Errorf("expected conn max age 1m, got %s", cfg.ConnMaxAge)
+	}
+	if cfg.User != "root" {
+		t.Errorf("expected unset keys to keep defaults, got user '%s'", cfg.User)
+	}
+}
+
+func TestConfig_ApplyReportsAllProblems(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"max_conections": 20,
+		"port":           "abc",
+		"password":       1234,
+	})
+
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	if len(cerr.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", cerr.Problems)
+	}
+	if !strings.Contains(err.Error(), `unknown key "max_conections"`) {
+		t.Errorf("expected unknown key to be named, got '%s'", err.Error())
+	}
+}
+
+func TestNewMySQLAdapterWithOptions(t *testing.T) {
+	a, err := NewMySQLAdapterWithOptions(
+		WithHost("db.internal"),
+		WithCredentials("app", "secret"),
+		WithMaxConnections(50),
+		WithConnMaxAge(5*time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.config.Host != "db.internal" || a.config.User != "app" || a.config.Password != "secret" {
+		t.Errorf("unexpected config %+v", a.config)
+	}
+	if a.config.MaxConnections != 50 || a.config.ConnMaxAge != 5*time.Minute {
+		t.Errorf("unexpected pool config %+v", a.config)
+	}
+	if a.config.Port != 3306 {
+		t.Errorf("expected default port, got %d", a.config.Port)
+	}
+
+	if _, err := NewMySQLAdapterWithOptions(WithPort(-1)); err == nil {
+		t.Error("expected error for invalid port")
+	}
+}
+
+func TestMySQLAdapter_ConnectRejectsUnknownKeys(t *testing.T) {
+	a := NewMySQLAdapter()
+	err := a.Connect(context.Background(), map[string]interface{}{"hots": "localhost"})
+	if !errors.Is(err, adapter.ErrConfiguration) {
+		t.Errorf("expected configuration error, got %v", err)
+	}
+}
+
+func TestMySQLAdapter_ConnectReportsAllProblems(t *testing.T) {
+	a := NewMySQLAdapter()
+	err := a.Connect(context.Background(), map[string]interface{}{
+		"hots":      "localhost",
+		"port":      70000,
+		"max_idle":  20,
+		"user":      "",
+		"lazy_mode": true,
+	})
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	// Two unknown keys, and the port, idle connections and user rejected by validation
+	if len(cerr.Problems) != 5 {
+		t.Errorf("expected 5 problems, got %v", cerr.Problems)
+	}
+	if a.config.Port != DefaultConfig().Port {
+		t.Error("expected the configuration to be left alone")
+	}
+}
+
+func TestConfig_ConnMaxAge(t *testing.T) {
+	for v, want := range map[interface{}]time.Duration{
+		"30m":      30 * time.Minute,
+		0.5:        500 * time.Millisecond,
+		"90":       90 * time.Second,
+		int64(120): 2 * time.Minute,
+	} {
+		cfg := DefaultConfig()
+		if err := cfg.apply(map[string]interface{}{"conn_max_age_seconds": v}); err != nil {
+			t.Fatalf("unexpected error for %v: %v", v, err)
+		}
+		if cfg.ConnMaxAge != want {
+			t.Errorf("expected %s for %v, got %s", want, v, cfg.ConnMaxAge)
+		}
+	}
+}
+
+func TestConfig_PoolSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"conn_max_age_jitter_seconds": 30,
+		"conn_max_idle_seconds":       float64(90),
+		"dial_timeout_seconds":        "500ms",
+		"read_timeout_seconds":        "2.5",
+		"write_timeout_seconds":       5,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ConnMaxAgeJitter != 30*time.Second || cfg.ConnMaxIdleTime != 90*time.Second {
+		t.Errorf("unexpected lifetime settings %+v", cfg)
+	}
+	if cfg.DialTimeout != 500*time.Millisecond || cfg.ReadTimeout != 2500*time.Millisecond || cfg.WriteTimeout != 5*time.Second {
+		t.Errorf("unexpected timeouts %+v", cfg)
+	}
+
+	driverCfg, err := cfg.driverConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if driverCfg.Timeout != 500*time.Millisecond || driverCfg.ReadTimeout != 2500*time.Millisecond {
+		t.Errorf("expected timeouts passed to the driver, got %+v", driverCfg)
+	}
+}
+
+func TestConfig_ValidatePool(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxConnections = 5
+	cfg.MaxIdle = 10
+	cfg.ConnMaxAgeJitter = 2 * time.Hour
+
+	err := cfg.Validate()
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	if len(cerr.Problems) != 2 {
+		t.Errorf("expected 2 problems, got %v", cerr.Problems)
+	}
+
+	// Unlimited connections allow any number of idle connections
+	cfg = DefaultConfig()
+	cfg.MaxConnections = 0
+	cfg.MaxIdle = 50
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConfig_SessionInit(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		ConfigSessionInit: map[string]interface{}{
+			"time_zone":          "+00:00",
+			"sql_mode":           "STRICT_ALL_TABLES,NO_ZERO_DATE",
+			"autocommit":         true,
+			"max_execution_time": 5000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SET SESSION autocommit = ON, max_execution_time = 5000, sql_mode = 'STRICT_ALL_TABLES,NO_ZERO_DATE', time_zone = '+00:00'"
+	if len(cfg.SessionInit) != 1 || cfg.SessionInit[0] != want {
+		t.Errorf("unexpected statements %q", cfg.SessionInit)
+	}
+
+	err = cfg.apply(map[string]interface{}{
+		ConfigSessionInit: []interface{}{"SET NAMES utf8mb4 COLLATE utf8mb4_0900_ai_ci"},
+	})
+	if err != nil || len(cfg.SessionInit) != 1 || cfg.SessionInit[0] != "SET NAMES utf8mb4 COLLATE utf8mb4_0900_ai_ci" {
+		t.Errorf("unexpected statements %q (err %v)", cfg.SessionInit, err)
+	}
+
+	for _, v := range []interface{}{
+		map[string]interface{}{"time_zone; DROP TABLE users": "x"},
+		map[string]interface{}{"sql_mode": []string{"a"}},
+		"SET NAMES utf8mb4",
+	} {
+		if err := cfg.apply(map[string]interface{}{ConfigSessionInit: v}); err == nil {
+			t.Errorf("expected %v to be rejected", v)
+		}
+	}
+
+	if got, _ := sessionValue("it's"); got != "'it''s'" {
+		t.Errorf("expected quote to be escaped, got %s", got)
+	}
+}
@@ -0,0 +1,443 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// connector opens the pool's connections through the driver's connector and
+// wraps each one so the adapter can attach per-connection behavior.
+type connector struct {
+	base driver.Connector
+
+	// cfg is the driver configuration used to build connectors for
+	// credentials returned by a provider.
+	cfg          *gomysql.Config
+	newConnector func(*gomysql.Config) (driver.Connector, error)
+
+	mu          sync.Mutex
+	credentials CredentialProvider
+	lastCreds   Credentials
+	lastAddr    string
+	lastBase    driver.Connector
+
+	// failover holds the candidate primaries, when more than one is
+	// configured.
+	failover *failover
+
+	// init holds statements run on every new connection before use.
+	init []string
+
+	// onConnect, when set, is told whether the server could be reached.
+	onConnect func(err error)
+
+	// onBadConn, when set, is called when a statement fails with
+	// driver.ErrBadConn and database/sql retries it on another connection.
+	onBadConn func()
+
+	// tracing, when set, records a span for every statement.
+	tracing *tracing
+
+	// comments, when set, appends sqlcommenter comments to statements.
+	comments *commenter
+
+	// killOnCancel kills statements on the server when their context ends.
+	killOnCancel bool
+
+	// lifetime and jitter give each connection its own maximum age,
+	// spreading reconnects out instead of recycling the pool at once.
+	lifetime time.Duration
+	jitter   time.Duration
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.open(ctx)
+	if c.onConnect != nil && ctx.Err() == nil {
+		c.onConnect(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := initSession(ctx, conn, c.init); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	pc := &pooledConn{Conn: conn, onBadConn: c.onBadConn, tracing: c.tracing, comments: c.comments}
+	if c.cfg != nil {
+		pc.addr = c.cfg.Addr
+	}
+	if c.failover != nil {
+		pc.failover = c.failover
+		pc.generation = c.failover.generation.Load()
+		pc.addr = c.failover.primary()
+	}
+	if c.lifetime > 0 {
+		pc.expires = time.Now().Add(c.lifetime - jitterDuration(c.jitter))
+	}
+	if c.killOnCancel {
+		// Without a thread ID statements cannot be killed, which is no
+		// reason to give up the connection
+		if id, err := connectionID(ctx, conn); err == nil {
+			var addr string
+			if c.failover != nil {
+				addr = pc.addr
+			}
+			pc.threadID = id
+			pc.kill = func(id uint64) { _ = c.killQuery(addr, id) }
+		}
+	}
+	return pc, nil
+}
+
+// open opens a driver connection with the current credentials, to the
+// current writable primary when failover hosts are configured.
+func (c *connector) open(ctx context.Context) (driver.Conn, error) {
+	if c.failover != nil {
+		return c.failover.open(ctx, c.openAddr)
+	}
+	return c.openAddr(ctx, "")
+}
+
+// openAddr opens a driver connection to addr, or to the configured address
+// when addr is empty.
+func (c *connector) openAddr(ctx context.Context, addr string) (driver.Conn, error) {
+	base, err := c.connectorFor(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return base.Connect(ctx)
+}
+
+// initSession runs the session initialization statements on conn.
+func initSession(ctx context.Context, conn driver.Conn, statements []string) error {
+	for _, stmt := range statements {
+		if err := execConn(ctx, conn, stmt); err != nil {
+			return fmt.Errorf("mysql: session init %q failed: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// execConn executes a statement without arguments on a driver connection.
+func execConn(ctx context.Context, conn driver.Conn, query string) error {
+	if e, ok := conn.(driver.ExecerContext); ok {
+		_, err := e.ExecContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+	_, err = stmt.Exec(nil) //nolint:staticcheck // fallback for drivers without ExecerContext
+	return err
+}
+
+// setCredentials replaces the credential provider used for new connections.
+func (c *connector) setCredentials(p CredentialProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = p
+	c.lastBase = nil
+}
+
+// connectorFor returns the driver connector for the next connection to
+// addr. With a credential provider, the credentials are looked up and a
+// connector is built for them, reusing the previous one while the
+// credentials and address are unchanged.
+func (c *connector) connectorFor(ctx context.Context, addr string) (driver.Connector, error) {
+	c.mu.Lock()
+	provider := c.credentials
+	c.mu.Unlock()
+	if provider == nil && addr == "" {
+		return c.base, nil
+	}
+
+	var creds Credentials
+	if provider != nil {
+		var err error
+		if creds, err = provider.Credentials(ctx); err != nil {
+			return nil, fmt.Errorf("mysql: failed to get credentials: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastBase != nil && c.lastCreds == creds && c.lastAddr == addr {
+		return c.lastBase, nil
+	}
+
+	cfg := c.cfg.Clone()
+	if provider != nil {
+		if creds.User != "" {
+			cfg.User = creds.User
+		}
+		cfg.Passwd = creds.Password
+	}
+	if addr != "" {
+		cfg.Addr = addr
+		// Let the driver derive the TLS server name from the new address
+		cfg.TLS = nil
+	}
+
+	newConnector := c.newConnector
+	if newConnector == nil {
+		newConnector = func(cfg *gomysql.Config) (driver.Connector, error) {
+			return gomysql.NewConnector(cfg)
+		}
+	}
+	base, err := newConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: invalid credentials: %w", err)
+	}
+	c.lastCreds = creds
+	c.lastAddr = addr
+	c.lastBase = base
+	return base, nil
+}
+
+// Driver implements driver.Connector.
+func (c *connector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// jitterDuration returns a random duration in [0, max).
+func jitterDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// pooledConn wraps a driver connection, forwarding the optional interfaces
+// database/sql looks for to the underlying connection.
+type pooledConn struct {
+	driver.Conn
+
+	// expires is when the pool should stop reusing the connection.
+	expires time.Time
+
+	// failover and generation detect connections to a demoted primary.
+	failover   *failover
+	generation uint64
+
+	onBadConn func()
+
+	// tracing and addr describe the connection's statement spans.
+	tracing *tracing
+	addr    string
+
+	// comments tag statements as they are sent. database/sql, and the
+	// statement spans, keep seeing the statements without comments.
+	comments *commenter
+
+	// threadID is the server thread of the connection, and kill stops the
+	// statement running on it from another connection. killed is set once a
+	// kill was issued, so the pool discards the connection rather than let
+	// the KILL reach another caller's statement.
+	threadID uint64
+	kill     func(threadID uint64)
+	killed   atomic.Bool
+}
+
+// stale reports whether the pool should discard the connection because it
+// expired, its primary was replaced or a statement on it was killed.
+func (c *pooledConn) stale() bool {
+	if c.killed.Load() {
+		return true
+	}
+	if !c.expires.IsZero() && time.Now().After(c.expires) {
+		return true
+	}
+	return c.failover != nil && c.failover.generation.Load() != c.generation
+}
+
+// observe inspects a statement error for signs that the primary was demoted
+// or that the statement will be retried.
+func (c *pooledConn) observe(err error) error {
+	if c.failover != nil && isReadOnlyError(err) {
+		c.failover.demote(c.generation, err)
+	}
+	if c.onBadConn != nil && errors.Is(err, driver.ErrBadConn) {
+		c.onBadConn()
+	}
+	return err
+}
+
+// IsValid implements driver.Validator. Expired connections are reported as
+// invalid so the pool closes them instead of handing them out again.
+func (c *pooledConn) IsValid() bool {
+	if c.stale() {
+		return false
+	}
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *pooledConn) ResetSession(ctx context.Context) error {
+	if c.stale() {
+		return driver.ErrBadConn
+	}
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *pooledConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	sent := c.comments.comment(ctx, query)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, sent)
+	} else {
+		stmt, err = c.Conn.Prepare(sent)
+	}
+	if err != nil {
+		return nil, c.observe(err)
+	}
+	if c.failover == nil && c.onBadConn == nil && c.tracing == nil && c.kill == nil {
+		return stmt, nil
+	}
+	return &pooledStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *pooledConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+	}
+	c.tracing.statement(ctx, start, c.addr, "BEGIN", nil, err)
+	if err != nil || c.tracing == nil {
+		return tx, err
+	}
+	return &pooledTx{Tx: tx, ctx: ctx, conn: c}, nil
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *pooledConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		start, live := time.Now(), ctx.Err() == nil
+		rows, err := q.QueryContext(ctx, c.comments.comment(ctx, query), args)
+		c.tracing.statement(ctx, start, c.addr, query, nil, err)
+		c.killIfCanceled(ctx, live, err)
+		return c.watchRows(ctx, rows), c.observe(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *pooledConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		start, live := time.Now(), ctx.Err() == nil
+		res, err := e.ExecContext(ctx, c.comments.comment(ctx, query), args)
+		c.tracing.statement(ctx, start, c.addr, query, res, err)
+		c.killIfCanceled(ctx, live, err)
+		return res, c.observe(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+// Ping implements driver.Pinger.
+func (c *pooledConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *pooledConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// pooledStmt wraps a prepared statement so statement errors reach the
+// connection's observer.
+type pooledStmt struct {
+	driver.Stmt
+	conn  *pooledConn
+	query string
+}
+
+// ExecContext implements driver.StmtExecContext.
+func (s *pooledStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start, live := time.Now(), ctx.Err() == nil
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err != nil {
+			return nil, err
+		}
+		res, err = s.Stmt.Exec(values) //nolint:staticcheck // fallback for drivers without StmtExecContext
+	}
+	s.conn.tracing.statement(ctx, start, s.conn.addr, s.query, res, err)
+	s.conn.killIfCanceled(ctx, live, err)
+	return res, s.conn.observe(err)
+}
+
+// QueryContext implements driver.StmtQueryContext.
+func (s *pooledStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start, live := time.Now(), ctx.Err() == nil
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err != nil {
+			return nil, err
+		}
+		rows, err = s.Stmt.Query(values) //nolint:staticcheck // fallback for drivers without StmtQueryContext
+	}
+	s.conn.tracing.statement(ctx, start, s.conn.addr, s.query, nil, err)
+	s.conn.killIfCanceled(ctx, live, err)
+	return s.conn.watchRows(ctx, rows), s.conn.observe(err)
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (s *pooledStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValues converts named arguments to positional values.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("mysql: named arguments are not supported")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
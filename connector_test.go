@@ -0,0 +1,305 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConn is a driver connection that records executed statements.
+type fakeConn struct {
+	execs   []string
+	execErr error
+	closed  bool
+	valid   bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.execs = append(c.execs, query)
+	if c.execErr != nil {
+		return nil, c.execErr
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) IsValid() bool {
+	return c.valid
+}
+
+// fakeConnector hands out fakeConns and remembers them.
+type fakeConnector struct {
+	conns   []*fakeConn
+	err     error
+	execErr error
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	conn := &fakeConn{valid: true, execErr: c.execErr}
+	c.conns = append(c.conns, conn)
+	return conn, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestConnector_Lifetime(t *testing.T) {
+	c := &connector{base: &fakeConnector{}, lifetime: time.Minute, jitter: 10 * time.Second}
+
+	before := time.Now()
+	conn, err := c.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pc := conn.(*pooledConn)
+
+	earliest := before.Add(50 * time.Second)
+	latest := time.Now().Add(time.Minute)
+	if pc.expires.Before(earliest) || pc.expires.After(latest) {
+		t.Errorf("expected expiry within jittered lifetime, got %s after connect", pc.expires.Sub(before))
+	}
+	if !pc.IsValid() {
+		t.Error("expected fresh connection to be valid")
+	}
+
+	pc.expires = time.Now().Add(-time.Second)
+	if pc.IsValid() {
+		t.Error("expected expired connection to be invalid")
+	}
+	if err := pc.ResetSession(context.Background()); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("expected ErrBadConn for expired connection, got %v", err)
+	}
+}
+
+func TestConnector_NoLifetime(t *testing.T) {
+	base := &fakeConnector{}
+	c := &connector{base: base}
+
+	conn, err := c.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pc := conn.(*pooledConn)
+	if !pc.expires.IsZero() {
+		t.Errorf("expected no expiry, got %s", pc.expires)
+	}
+
+	// Validity of the underlying connection is still honored
+	base.conns[0].valid = false
+	if pc.IsValid() {
+		t.Error("expected invalid underlying connection to be reported")
+	}
+}
+
+func TestPooledConn_Forwarding(t *testing.T) {
+	fc := &fakeConn{valid: true}
+	pc := &pooledConn{Conn: fc}
+
+	if _, err := pc.ExecContext(context.Background(), "SET @a = 1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.execs) != 1 || fc.execs[0] != "SET @a = 1" {
+		t.Errorf("expected exec to be forwarded, got %v", fc.execs)
+	}
+
+	// The fake connection has no QueryerContext, so database/sql must fall back
+	if _, err := pc.QueryContext(context.Background(), "SELECT 1", nil); !errors.Is(err, driver.ErrSkip) {
+		t.Errorf("expected ErrSkip, got %v", err)
+	}
+}
+
+func TestJitterDuration(t *testing.T) {
+	if d := jitterDuration(0); d != 0 {
+		t.Errorf("expected no jitter, got %s", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := jitterDuration(time.Second); d < 0 || d >= time.Second {
+			t.Fatalf("jitter %s out of range", d)
+		}
+	}
+}
+
+func TestConnector_SessionInit(t *testing.T) {
+	base := &fakeConnector{}
+	c := &connector{base: base, init: []string{"SET NAMES utf8mb4", "SET SESSION time_zone = '+00:00'"}}
+
+	if _, err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := base.conns[0].execs; len(got) != 2 || got[0] != "SET NAMES utf8mb4" {
+		t.Errorf("expected init statements in order, got %v", got)
+	}
+
+	base.execErr = errors.New("Unknown system variable")
+	if _, err := c.Connect(context.Background()); err == nil {
+		t.Fatal("expected failing init statement to fail the connection")
+	}
+	if !base.conns[1].closed {
+		t.Error("expected connection to be closed after failed init")
+	}
+}
+
+// fakeResult is the canned response of a scriptConnector to a query.
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	// exec is the result of a statement; nil reports one affected row.
+	exec driver.Result
+}
+
+// fakeExec is the result of a statement run by a scriptConnector.
+type fakeExec struct {
+	lastID   int64
+	affected int64
+}
+
+func (r fakeExec) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r fakeExec) RowsAffected() (int64, error) { return r.affected, nil }
+
+// scriptConnector hands out connections answering queries from a script
+// of canned results, keyed by the exact query text.
+type scriptConnector struct {
+	mu      sync.Mutex
+	results map[string]fakeResult
+	queries []string
+}
+
+func (c *scriptConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &scriptConn{connector: c}, nil
+}
+
+func (c *scriptConnector) Driver() driver.Driver {
+	return nil
+}
+
+// executed returns the queries run so far.
+func (c *scriptConnector) executed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.queries...)
+}
+
+// respond records query and returns its canned result.
+func (c *scriptConnector) respond(query string) (fakeResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+	res, ok := c.results[query]
+	if !ok {
+		return fakeResult{}, fmt.Errorf("unexpected query %q", query)
+	}
+	return res, res.err
+}
+
+// scriptConn is a connection of a scriptConnector.
+type scriptConn struct {
+	connector *scriptConnector
+}
+
+func (c *scriptConn) Prepare(query string) (driver.Stmt, error) {
+	return &scriptStmt{conn: c, query: query}, nil
+}
+
+// scriptStmt is a prepared statement answered from its connector's script.
+type scriptStmt struct {
+	conn  *scriptConn
+	query string
+}
+
+func (s *scriptStmt) Close() error  { return nil }
+func (s *scriptStmt) NumInput() int { return -1 }
+
+func (s *scriptStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *scriptStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+func (c *scriptConn) Close() error {
+	return nil
+}
+
+func (c *scriptConn) Begin() (driver.Tx, error) {
+	return &fakeTx{connector: c.connector}, nil
+}
+
+// fakeTx is a transaction recording its end among the executed queries.
+type fakeTx struct {
+	connector *scriptConnector
+}
+
+func (t *fakeTx) Commit() error   { return t.end("COMMIT") }
+func (t *fakeTx) Rollback() error { return t.end("ROLLBACK") }
+
+func (t *fakeTx) end(query string) error {
+	t.connector.mu.Lock()
+	defer t.connector.mu.Unlock()
+	t.connector.queries = append(t.connector.queries, query)
+	return nil
+}
+
+func (c *scriptConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.connector.respond(query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+func (c *scriptConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.connector.respond(query)
+	if err != nil {
+		return nil, err
+	}
+	if res.exec != nil {
+		return res.exec, nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// fakeRows iterates over canned rows.
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// writeToken remembers the writes made through a read-your-writes context.
+type writeToken struct {
+	mu    sync.Mutex
+	wrote bool
+	gtid  string
+}
+
+// record adds the GTID of a write, or replaces the set with the primary's
+// executed GTID set, which contains every earlier write. An empty set (GTIDs
+// disabled) still marks the context as having written.
+func (t *writeToken) record(gtid string, own bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.wrote = true
+	if own && t.gtid != "" {
+		t.gtid += "," + gtid
+		return
+	}
+	t.gtid = gtid
+}
+
+// state returns whether the context has written and the GTID set to wait for.
+func (t *writeToken) state() (bool, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.wrote, t.gtid
+}
+
+// writeTokenKey is the context key of the read-your-writes token.
+type writeTokenKey struct{}
+
+// WithReadYourWrites returns a context in which reads observe earlier
+// writes made with the same context. After a write, reads wait for a
+// replica to apply it (up to gtid_wait_timeout_seconds) or go to the
+// primary.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if tokenFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, writeTokenKey{}, &writeToken{})
+}
+
+// LastGTID returns the GTID set covering the writes made with ctx, or "" if
+// there is none.
+func LastGTID(ctx context.Context) string {
+	if t := tokenFrom(ctx); t != nil {
+		_, gtid := t.state()
+		return gtid
+	}
+	return ""
+}
+
+// tokenFrom returns the read-your-writes token of ctx, if any.
+func tokenFrom(ctx context.Context) *writeToken {
+	t, _ := ctx.Value(writeTokenKey{}).(*writeToken)
+	return t
+}
+
+// ownGTIDQuery reads the GTID of the last transaction committed by the
+// session. The driver does not expose the session_track_gtids tracker, so it
+// is taken from the Performance Schema.
+const ownGTIDQuery = "SELECT GTID FROM performance_schema.events_transactions_current " +
+	"WHERE THREAD_ID = (SELECT THREAD_ID FROM performance_schema.threads WHERE PROCESSLIST_ID = CONNECTION_ID())"
+
+// tracksWrites reports whether the writes made with ctx are tracked for
+// read-your-writes consistency.
+func (a *MySQLAdapter) tracksWrites(ctx context.Context) bool {
+	return tokenFrom(ctx) != nil && a.replicas != nil
+}
+
+// trackedConn returns a primary connection to pin a write to when its GTID
+// is tracked, or nil when ctx does not track writes.
+func (a *MySQLAdapter) trackedConn(ctx context.Context) (*sql.Conn, error) {
+	if !a.tracksWrites(ctx) {
+		return nil, nil
+	}
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: failed to acquire connection: %w", err)
+	}
+	return conn, nil
+}
+
+// trackWrite records the GTID of the write just committed on conn in the
+// context's read-your-writes token. When the session's own GTID is not
+// available (the Performance Schema is disabled), the primary's executed GTID
+// set is read on the same connection instead: it contains the write's GTID
+// and may include later transactions. A nil conn records nothing.
+func (a *MySQLAdapter) trackWrite(ctx context.Context, conn *sql.Conn) {
+	t := tokenFrom(ctx)
+	if t == nil || conn == nil {
+		return
+	}
+
+	var gtid sql.NullString
+	err := conn.QueryRowContext(ctx, ownGTIDQuery).Scan(&gtid)
+	if err == nil && isGTID(gtid.String) {
+		t.record(gtid.String, true)
+		return
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_executed").Scan(&gtid); err != nil {
+		gtid.String = ""
+	}
+	t.record(gtid.String, false)
+}
+
+// isGTID reports whether s is an assigned GTID (source UUID and transaction
+// number) rather than AUTOMATIC or ANONYMOUS.
+func isGTID(s string) bool {
+	return strings.Contains(s, ":")
+}
+
+// waitForGTID waits on conn until the replica has applied gtid, reporting
+// whether it did so before the configured timeout.
+func (a *MySQLAdapter) waitForGTID(ctx context.Context, conn *sql.Conn, gtid string) bool {
+	var timedOut sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)",
+		gtid, a.config.GTIDWaitTimeout.Seconds()).Scan(&timedOut)
+	return err == nil && timedOut.Valid && timedOut.Int64 == 0
+}
@@ -0,0 +1,204 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+const (
+	gtidQuery = "SELECT @@GLOBAL.gtid_executed"
+	waitQuery = "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)"
+)
+
+// ownGTID is the scripted GTID of the session's last write.
+const ownGTID = "3e11fa47-71ca-11e1-9e33-c80aa9429562:42"
+
+// consistencyAdapter returns an adapter with a scripted primary and replica.
+func consistencyAdapter(t *testing.T, waitResult int64) (*MySQLAdapter, *scriptConnector) {
+	a, _, replica := consistencyAdapters(t, waitResult)
+	return a, replica
+}
+
+// consistencyAdapters returns an adapter with its scripted primary and replica.
+func consistencyAdapters(t *testing.T, waitResult int64) (*MySQLAdapter, *scriptConnector, *scriptConnector) {
+	t.Helper()
+	primary := &scriptConnector{results: map[string]fakeResult{
+		ownGTIDQuery: {columns: []string{"GTID"}, rows: [][]driver.Value{{ownGTID}}},
+		gtidQuery:    {columns: []string{"gtid"}, rows: [][]driver.Value{{"3e11fa47-71ca-11e1-9e33-c80aa9429562:1-42"}}},
+	}}
+	replicaConn := &scriptConnector{results: map[string]fakeResult{
+		waitQuery: {columns: []string{"result"}, rows: [][]driver.Value{{waitResult}}},
+	}}
+
+	a := NewMySQLAdapter()
+	a.db = sql.OpenDB(primary)
+	a.replicas = &replicaSet{replicas: []*replica{{name: "r", db: sql.OpenDB(replicaConn)}}}
+	t.Cleanup(func() {
+		a.replicas.close()
+		_ = a.db.Close()
+	})
+	return a, primary, replicaConn
+}
+
+// trackTestWrite records a write made with ctx on a primary connection.
+func trackTestWrite(t *testing.T, a *MySQLAdapter, ctx context.Context) {
+	t.Helper()
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	a.trackWrite(ctx, conn)
+}
+
+func TestMySQLAdapter_TrackWrite(t *testing.T) {
+	a, primary, _ := consistencyAdapters(t, 0)
+
+	trackTestWrite(t, a, context.Background())
+	if got := primary.executed(); len(got) != 0 {
+		t.Errorf("expected nothing to be read without a token, got %v", got)
+	}
+
+	ctx := WithReadYourWrites(context.Background())
+	if WithReadYourWrites(ctx) != ctx {
+		t.Error("expected existing token to be reused")
+	}
+	if q, _, _ := a.reader(ctx); q != a.replicas.replicas[0].db {
+		t.Error("expected replica before any write")
+	}
+
+	// The session's own GTIDs accumulate
+	trackTestWrite(t, a, ctx)
+	trackTestWrite(t, a, ctx)
+	if got := LastGTID(ctx); got != ownGTID+","+ownGTID {
+		t.Errorf("unexpected gtid %q", got)
+	}
+	if LastGTID(context.Background()) != "" {
+		t.Error("expected no gtid without a token")
+	}
+
+	// Without the Performance Schema the executed set is used
+	primary.results[ownGTIDQuery] = fakeResult{columns: []string{"GTID"}, rows: [][]driver.Value{{"AUTOMATIC"}}}
+	trackTestWrite(t, a, ctx)
+	if got := LastGTID(ctx); got != "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-42" {
+		t.Errorf("unexpected gtid %q", got)
+	}
+}
+
+func TestMySQLAdapter_TrackWriteAfterSuccess(t *testing.T) {
+	a, primary, _ := consistencyAdapters(t, 0)
+	update := "UPDATE users SET name = ? WHERE id = ?"
+	primary.results[update] = fakeResult{exec: fakeExec{}}
+	primary.results["SELECT COUNT(*) AS matched FROM users WHERE id = ?"] = fakeResult{columns: []string{"matched"}, rows: [][]driver.Value{{int64(0)}}}
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+	}
+	ctx := WithReadYourWrites(context.Background())
+
+	// A failed write is not recorded
+	if err := a.Update(ctx, op, []interface{}{map[string]interface{}{"ID": 1, "Name": "Ann"}}); !errors.Is(err, adapter.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if wrote, _ := tokenFrom(ctx).state(); wrote {
+		t.Error("expected failed write not to be tracked")
+	}
+
+	primary.results[update] = fakeResult{exec: fakeExec{affected: 1}}
+	if err := a.Update(ctx, op, []interface{}{map[string]interface{}{"ID": 1, "Name": "Ann"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := LastGTID(ctx); got != ownGTID {
+		t.Errorf("unexpected gtid %q", got)
+	}
+	if got := primary.executed(); got[len(got)-1] != ownGTIDQuery {
+		t.Errorf("expected the GTID to be read after the write, got %v", got)
+	}
+}
+
+func TestMySQLAdapter_ReaderAfterWrite(t *testing.T) {
+	t.Run("no wait timeout reads primary", func(t *testing.T) {
+		a, replica := consistencyAdapter(t, 0)
+		ctx := WithReadYourWrites(context.Background())
+		trackTestWrite(t, a, ctx)
+
+		q, _, release := a.reader(ctx)
+		defer release()
+		if q != a.db {
+			t.Error("expected primary")
+		}
+		if len(replica.executed()) != 0 {
+			t.Error("expected no wait on the replica")
+		}
+	})
+
+	t.Run("replica caught up", func(t *testing.T) {
+		a, replica := consistencyAdapter(t, 0)
+		a.config.GTIDWaitTimeout = 500 * time.Millisecond
+		ctx := WithReadYourWrites(context.Background())
+		trackTestWrite(t, a, ctx)
+
+		q, _, release := a.reader(ctx)
+		defer release()
+		if _, ok := q.(*sql.Conn); !ok {
+			t.Errorf("expected pinned replica connection, got %T", q)
+		}
+		if got := replica.executed(); len(got) != 1 || got[0] != waitQuery {
+			t.Errorf("expected gtid wait, got %v", got)
+		}
+	})
+
+	t.Run("replica timed out", func(t *testing.T) {
+		a, _ := consistencyAdapter(t, 1)
+		a.config.GTIDWaitTimeout = 500 * time.Millisecond
+		ctx := WithReadYourWrites(context.Background())
+		trackTestWrite(t, a, ctx)
+
+		q, _, release := a.reader(ctx)
+		defer release()
+		if q != a.db {
+			t.Error("expected fallback to primary")
+		}
+	})
+}
+
+func TestReplicaLag(t *testing.T) {
+	status := func(lag driver.Value) *scriptConnector {
+		return &scriptConnector{results: map[string]fakeResult{
+			"SHOW REPLICA STATUS": {
+				columns: []string{"Replica_IO_State", "Seconds_Behind_Source"},
+				rows:    [][]driver.Value{{"Waiting for source", lag}},
+			},
+		}}
+	}
+
+	db := sql.OpenDB(status("7"))
+	defer func() { _ = db.Close() }()
+	lag, err := replicaLag(context.Background(), db)
+	if err != nil || lag != 7*time.Second {
+		t.Errorf("expected 7s lag, got %s (%v)", lag, err)
+	}
+
+	stopped := sql.OpenDB(status(nil))
+	defer func() { _ = stopped.Close() }()
+	if _, err := replicaLag(context.Background(), stopped); err == nil {
+		t.Error("expected error when replication is not running")
+	}
+
+	set := &replicaSet{replicas: []*replica{{name: "ok", db: db}, {name: "stopped", db: stopped}}}
+	set.checkLag(10*time.Second, time.Second)
+	if set.replicas[0].excluded.Load() || !set.replicas[1].excluded.Load() {
+		t.Error("expected only the stopped replica to be excluded")
+	}
+	set.checkLag(5*time.Second, time.Second)
+	if !set.replicas[0].excluded.Load() {
+		t.Error("expected lagging replica to be excluded")
+	}
+}
@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are the user name and password used to open a connection.
+// An empty User keeps the configured user.
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CredentialProvider supplies credentials whenever the pool opens a new
+// connection, so rotated secrets and short-lived tokens are picked up
+// without reconnecting the adapter.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// EnvCredentials reads credentials from environment variables.
+type EnvCredentials struct {
+	// PasswordVar names the variable holding the password.
+	PasswordVar string
+
+	// UserVar optionally names the variable holding the user.
+	UserVar string
+}
+
+// Credentials implements CredentialProvider.
+func (p EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	password, ok := os.LookupEnv(p.PasswordVar)
+	if !ok {
+		return Credentials{}, fmt.Errorf("mysql: environment variable %s is not set", p.PasswordVar)
+	}
+	creds := Credentials{Password: password}
+	if p.UserVar != "" {
+		creds.User = os.Getenv(p.UserVar)
+	}
+	return creds, nil
+}
+
+// FileCredentials reads the password from a file, such as a mounted
+// Kubernetes or Docker secret. The file is re-read for every connection and
+// surrounding whitespace is trimmed.
+type FileCredentials struct {
+	PasswordFile string
+}
+
+// Credentials implements CredentialProvider.
+func (p FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	data, err := os.ReadFile(p.PasswordFile)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("mysql: failed to read password file: %w", err)
+	}
+	return Credentials{Password: strings.TrimSpace(string(data))}, nil
+}
+
+// ExecCredentials runs a command and uses its trimmed standard output as the
+// password, for example to generate a cloud IAM authentication token.
+type ExecCredentials struct {
+	Command []string
+}
+
+// Credentials implements CredentialProvider.
+func (p ExecCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	if len(p.Command) == 0 {
+		return Credentials{}, errors.New("mysql: password command is empty")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Credentials{}, fmt.Errorf("mysql: password command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return Credentials{Password: strings.TrimSpace(stdout.String())}, nil
+}
+
+// cachedCredentials reuses credentials from a provider for a fixed time.
+type cachedCredentials struct {
+	provider CredentialProvider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	creds   Credentials
+	expires time.Time
+}
+
+// NewCachedCredentials wraps provider so its credentials are reused for ttl,
+// avoiding a lookup for every new connection.
+func NewCachedCredentials(provider CredentialProvider, ttl time.Duration) CredentialProvider {
+	return &cachedCredentials{provider: provider, ttl: ttl, now: time.Now}
+}
+
+// Credentials implements CredentialProvider.
+func (p *cachedCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.now().Before(p.expires) {
+		return p.creds, nil
+	}
+	creds, err := p.provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	p.creds = creds
+	p.expires = p.now().Add(p.ttl)
+	return creds, nil
+}
+
+// SetCredentialProvider replaces the source of connection credentials.
+// Connections opened after the call use the new provider.
+func (a *MySQLAdapter) SetCredentialProvider(p CredentialProvider) {
+	a.config.Credentials = p
+	if a.connector != nil {
+		a.connector.setCredentials(p)
+	}
+	if a.replicas != nil {
+		for _, r := range a.replicas.replicas {
+			r.connector.setCredentials(p)
+		}
+	}
+}
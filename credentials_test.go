@@ -0,0 +1,198 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// staticCredentials returns fixed credentials and counts lookups.
+type staticCredentials struct {
+	creds Credentials
+	err   error
+	calls int
+}
+
+func (p *staticCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	p.calls++
+	return p.creds, p.err
+}
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("TEST_MYSQL_USER", "app")
+	t.Setenv("TEST_MYSQL_PASSWORD", "s3cret")
+
+	creds, err := EnvCredentials{PasswordVar: "TEST_MYSQL_PASSWORD", UserVar: "TEST_MYSQL_USER"}.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds != (Credentials{User: "app", Password: "s3cret"}) {
+		t.Errorf("unexpected credentials: %+v", creds)
+	}
+
+	if _, err := (EnvCredentials{PasswordVar: "TEST_MYSQL_MISSING"}).Credentials(context.Background()); err == nil {
+		t.Error("expected error for unset variable")
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	p := FileCredentials{PasswordFile: path}
+
+	for _, want := range []string{"first", "rotated"} {
+		if err := os.WriteFile(path, []byte(want+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		creds, err := p.Credentials(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if creds.Password != want {
+			t.Errorf("expected password %q, got %q", want, creds.Password)
+		}
+	}
+
+	if _, err := (FileCredentials{PasswordFile: filepath.Join(t.TempDir(), "missing")}).Credentials(context.Background()); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestExecCredentials(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	creds, err := ExecCredentials{Command: []string{"sh", "-c", "echo token-123"}}.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Password != "token-123" {
+		t.Errorf("expected password from stdout, got %q", creds.Password)
+	}
+
+	_, err = ExecCredentials{Command: []string{"sh", "-c", "echo denied >&2; exit 1"}}.Credentials(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("expected failure with stderr, got %v", err)
+	}
+
+	if _, err := (ExecCredentials{}).Credentials(context.Background()); err == nil {
+		t.Error("expected error for empty command")
+	}
+}
+
+func TestCachedCredentials(t *testing.T) {
+	inner := &staticCredentials{creds: Credentials{Password: "a"}}
+	now := time.Unix(1000, 0)
+	p := NewCachedCredentials(inner, time.Minute).(*cachedCredentials)
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Credentials(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected 1 lookup within ttl, got %d", inner.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	inner.creds.Password = "b"
+	creds, _ := p.Credentials(context.Background())
+	if creds.Password != "b" || inner.calls != 2 {
+		t.Errorf("expected refreshed credentials after ttl, got %q after %d lookups", creds.Password, inner.calls)
+	}
+}
+
+func TestConnector_Credentials(t *testing.T) {
+	provider := &staticCredentials{creds: Credentials{User: "rotated", Password: "one"}}
+	var built []*gomysql.Config
+	c := &connector{
+		base:        &fakeConnector{err: errors.New("static connector used")},
+		cfg:         &gomysql.Config{User: "app", Passwd: "old", Net: "tcp", Addr: "db:3306"},
+		credentials: provider,
+		newConnector: func(cfg *gomysql.Config) (driver.Connector, error) {
+			built = append(built, cfg)
+			return &fakeConnector{}, nil
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Connect(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected provider consulted per connection, got %d calls", provider.calls)
+	}
+	if len(built) != 1 {
+		t.Fatalf("expected connector reused for unchanged credentials, built %d", len(built))
+	}
+	if built[0].User != "rotated" || built[0].Passwd != "one" {
+		t.Errorf("unexpected credentials %s/%s", built[0].User, built[0].Passwd)
+	}
+	if c.cfg.Passwd != "old" {
+		t.Error("expected base driver config to be left untouched")
+	}
+
+	provider.creds.Password = "two"
+	if _, err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(built) != 2 || built[1].Passwd != "two" {
+		t.Error("expected rotated password to be used for the next connection")
+	}
+
+	provider.err = errors.New("vault unavailable")
+	if _, err := c.Connect(context.Background()); err == nil || !strings.Contains(err.Error(), "vault unavailable") {
+		t.Errorf("expected provider error, got %v", err)
+	}
+
+	c.setCredentials(nil)
+	if _, err := c.Connect(context.Background()); err == nil || !strings.Contains(err.Error(), "static connector") {
+		t.Errorf("expected static connector without provider, got %v", err)
+	}
+}
+
+func TestConfig_CredentialProvider(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.credentialProvider() != nil {
+		t.Error("expected no provider by default")
+	}
+
+	if err := cfg.apply(map[string]interface{}{
+		ConfigPasswordCommand: []interface{}{"aws", "rds", "generate-db-auth-token"},
+		ConfigCredentialsTTL:  600,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cached, ok := cfg.credentialProvider().(*cachedCredentials)
+	if !ok || cached.ttl != 10*time.Minute {
+		t.Fatalf("expected cached provider, got %T", cfg.credentialProvider())
+	}
+	if cmd, ok := cached.provider.(ExecCredentials); !ok || len(cmd.Command) != 3 {
+		t.Errorf("unexpected provider %#v", cached.provider)
+	}
+
+	explicit := &staticCredentials{}
+	cfg = DefaultConfig()
+	WithCredentialProvider(explicit)(&cfg)
+	cfg.PasswordFile = "/run/secrets/mysql"
+	if cfg.credentialProvider() != explicit {
+		t.Error("expected explicit provider to take precedence")
+	}
+
+	cfg = DefaultConfig()
+	cfg.PasswordEnv = "MYSQL_PASSWORD"
+	cfg.PasswordFile = "/run/secrets/mysql"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "only one of") {
+		t.Errorf("expected conflicting sources to be rejected, got %v", err)
+	}
+}
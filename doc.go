@@ -66,6 +66,9 @@
 //   - max_connections: Maximum open connections
 //   - max_idle: Maximum idle connections
 //   - conn_max_age_seconds: Connection lifetime
+//   - conn_max_age_jitter_seconds: Random lifetime reduction per connection
+//   - conn_max_idle_seconds: Idle connection timeout
+//   - dial_timeout_seconds, read_timeout_seconds, write_timeout_seconds: Network timeouts
 //
 // # Thread Safety
 //
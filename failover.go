@@ -0,0 +1,228 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// FailoverEvent describes a switch of the adapter's primary to another host.
+type FailoverEvent struct {
+	// From is the address of the previous primary.
+	From string
+
+	// To is the address of the new writable primary.
+	To string
+
+	// Reason is the error that revealed the previous primary was demoted
+	// or unreachable.
+	Reason error
+
+	// Time is when the adapter switched.
+	Time time.Time
+}
+
+// FailoverFunc is called after the adapter switches to a new primary.
+type FailoverFunc func(FailoverEvent)
+
+// errReadOnly is returned when a candidate primary is read-only.
+var errReadOnly = errors.New("mysql: server is read-only")
+
+// failover picks the writable host among the candidate primaries. A new
+// connection goes to the current host if it is writable, otherwise to the
+// next writable candidate, which then becomes current.
+type failover struct {
+	hosts   []string
+	onEvent FailoverFunc
+
+	// drain is called after a switch to close idle connections to the old
+	// primary.
+	drain func()
+
+	// generation is bumped on every switch; connections opened under an
+	// older generation are discarded by the pool.
+	generation atomic.Uint64
+
+	mu      sync.Mutex
+	current int
+	active  string
+	reason  error
+}
+
+// open connects to the first writable candidate, starting with the current
+// one, using dial to open a connection to an address.
+func (f *failover) open(ctx context.Context, dial func(context.Context, string) (driver.Conn, error)) (driver.Conn, error) {
+	f.mu.Lock()
+	start := f.current
+	f.mu.Unlock()
+
+	var lastErr error
+	for i := range f.hosts {
+		idx := (start + i) % len(f.hosts)
+		addr := f.hosts[idx]
+
+		conn, err := dial(ctx, addr)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", addr, err)
+			continue
+		}
+		readOnly, err := isReadOnly(ctx, conn)
+		if err == nil && readOnly {
+			err = errReadOnly
+		}
+		if err != nil {
+			_ = conn.Close()
+			lastErr = fmt.Errorf("%s: %w", addr, err)
+			continue
+		}
+
+		f.connected(idx, lastErr)
+		return conn, nil
+	}
+	return nil, fmt.Errorf("mysql: no writable primary among %s: %w", strings.Join(f.hosts, ", "), lastErr)
+}
+
+// connected records a writable connection to hosts[idx], switching to it if
+// it is not the active primary.
+func (f *failover) connected(idx int, lastErr error) {
+	f.mu.Lock()
+	addr := f.hosts[idx]
+	f.current = idx
+	if f.active == addr {
+		f.mu.Unlock()
+		return
+	}
+
+	from := f.active
+	f.active = addr
+	reason := f.reason
+	if reason == nil {
+		reason = lastErr
+	}
+	f.reason = nil
+	f.mu.Unlock()
+
+	// The first connection is not a switch
+	if from == "" {
+		return
+	}
+
+	f.generation.Add(1)
+	if f.drain != nil {
+		go f.drain()
+	}
+	if f.onEvent != nil {
+		f.onEvent(FailoverEvent{From: from, To: addr, Reason: reason, Time: time.Now()})
+	}
+}
+
+// primary returns the address of the active primary.
+func (f *failover) primary() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+// demote marks the current primary as demoted after a connection opened
+// under generation saw err, so the next connection looks for a new one.
+func (f *failover) demote(generation uint64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.generation.Load() != generation || f.reason != nil {
+		return
+	}
+	f.reason = err
+	f.current = (f.current + 1) % len(f.hosts)
+	f.generation.Add(1)
+	if f.drain != nil {
+		go f.drain()
+	}
+}
+
+// isReadOnly reports whether the server behind conn has read_only or
+// super_read_only enabled.
+func isReadOnly(ctx context.Context, conn driver.Conn) (bool, error) {
+	rows, err := queryConn(ctx, conn, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('read_only', 'super_read_only')")
+	if err != nil {
+		return false, err
+	}
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		value := strings.ToUpper(asString(row[1]))
+		if value == "ON" || value == "1" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// asString converts a raw driver value to a string.
+func asString(v driver.Value) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// queryConn runs a query without arguments on a driver connection and
+// returns all rows.
+func queryConn(ctx context.Context, conn driver.Conn, query string) ([][]driver.Value, error) {
+	var rows driver.Rows
+	var err error
+	if q, ok := conn.(driver.QueryerContext); ok {
+		rows, err = q.QueryContext(ctx, query, nil)
+	} else {
+		err = driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		var stmt driver.Stmt
+		if stmt, err = conn.Prepare(query); err != nil {
+			return nil, err
+		}
+		defer func() { _ = stmt.Close() }()
+		rows, err = stmt.Query(nil) //nolint:staticcheck // fallback for drivers without QueryerContext
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result [][]driver.Value
+	for {
+		row := make([]driver.Value, len(rows.Columns()))
+		if err := rows.Next(row); err != nil {
+			if err == io.EOF {
+				return result, nil
+			}
+			return nil, err
+		}
+		result = append(result, row)
+	}
+}
+
+// isReadOnlyError reports whether err means the server refused a write
+// because it is read-only: ER_OPTION_PREVENTS_STATEMENT (1290) for
+// --read-only or --super-read-only, or ER_READ_ONLY_MODE (1836).
+func isReadOnlyError(err error) bool {
+	var mysqlErr *gomysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1836:
+		return true
+	case 1290:
+		return strings.Contains(mysqlErr.Message, "read-only") || strings.Contains(mysqlErr.Message, "read_only")
+	}
+	return false
+}
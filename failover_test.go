@@ -0,0 +1,167 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+const readOnlyQuery = "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('read_only', 'super_read_only')"
+
+// fakeHosts simulates candidate primaries whose read-only state can change.
+type fakeHosts struct {
+	mu       sync.Mutex
+	readOnly map[string]string
+	down     map[string]bool
+}
+
+func (h *fakeHosts) set(addr, superReadOnly string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readOnly[addr] = superReadOnly
+}
+
+func (h *fakeHosts) dial(ctx context.Context, addr string) (driver.Conn, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.down[addr] {
+		return nil, errors.New("connection refused")
+	}
+	c := &scriptConnector{results: map[string]fakeResult{
+		readOnlyQuery: {
+			columns: []string{"Variable_name", "Value"},
+			rows: [][]driver.Value{
+				{[]byte("read_only"), []byte("OFF")},
+				{[]byte("super_read_only"), []byte(h.readOnly[addr])},
+			},
+		},
+	}}
+	return c.Connect(ctx)
+}
+
+func TestFailover_Open(t *testing.T) {
+	hosts := &fakeHosts{
+		readOnly: map[string]string{"a:3306": "ON", "b:3306": "OFF"},
+		down:     map[string]bool{},
+	}
+	var events []FailoverEvent
+	drained := make(chan struct{}, 4)
+	f := &failover{
+		hosts:   []string{"a:3306", "b:3306"},
+		onEvent: func(e FailoverEvent) { events = append(events, e) },
+		drain:   func() { drained <- struct{}{} },
+	}
+
+	if _, err := f.open(context.Background(), hosts.dial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.active != "b:3306" || len(events) != 0 {
+		t.Fatalf("expected initial connection to writable host without event, got %s %v", f.active, events)
+	}
+
+	// Promote a and demote b
+	hosts.set("a:3306", "OFF")
+	hosts.set("b:3306", "ON")
+	before := f.generation.Load()
+	if _, err := f.open(context.Background(), hosts.dial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].From != "b:3306" || events[0].To != "a:3306" {
+		t.Fatalf("expected failover event from b to a, got %+v", events)
+	}
+	if !errors.Is(events[0].Reason, errReadOnly) {
+		t.Errorf("expected read-only reason, got %v", events[0].Reason)
+	}
+	if f.generation.Load() == before {
+		t.Error("expected generation to change on failover")
+	}
+	<-drained
+
+	hosts.set("a:3306", "ON")
+	if _, err := f.open(context.Background(), hosts.dial); err == nil {
+		t.Error("expected error when no host is writable")
+	}
+}
+
+func TestFailover_Demote(t *testing.T) {
+	hosts := &fakeHosts{
+		readOnly: map[string]string{"a:3306": "OFF", "b:3306": "OFF"},
+		down:     map[string]bool{},
+	}
+	var events []FailoverEvent
+	f := &failover{
+		hosts:   []string{"a:3306", "b:3306"},
+		onEvent: func(e FailoverEvent) { events = append(events, e) },
+	}
+	if _, err := f.open(context.Background(), hosts.dial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pc := &pooledConn{Conn: &fakeConn{valid: true}, failover: f, generation: f.generation.Load()}
+	readOnlyErr := &gomysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --super-read-only option so it cannot execute this statement"}
+	if err := pc.observe(readOnlyErr); err != readOnlyErr {
+		t.Error("expected the statement error to be returned unchanged")
+	}
+	if pc.IsValid() {
+		t.Error("expected connection to the demoted primary to be invalid")
+	}
+	pc.observe(readOnlyErr)
+
+	if _, err := f.open(context.Background(), hosts.dial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].To != "b:3306" || events[0].Reason != readOnlyErr {
+		t.Errorf("expected a single failover to b caused by the statement error, got %+v", events)
+	}
+}
+
+func TestIsReadOnlyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&gomysql.MySQLError{Number: 1836, Message: "Running in read-only mode"}, true},
+		{&gomysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}, true},
+		{&gomysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --secure-file-priv option so it cannot execute this statement"}, false},
+		{fmt.Errorf("mysql: execute failed: %w", &gomysql.MySQLError{Number: 1836}), true},
+		{&gomysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{errors.New("read-only"), false},
+	}
+	for _, tt := range tests {
+		if got := isReadOnlyError(tt.err); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestConfig_Hosts(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.apply(map[string]interface{}{
+		ConfigHosts: []interface{}{"db-1.internal", "db-2.internal:3307"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addrs := cfg.hostAddrs()
+	if len(addrs) != 2 || addrs[0] != "db-1.internal:3306" || addrs[1] != "db-2.internal:3307" {
+		t.Errorf("unexpected addresses %v", addrs)
+	}
+
+	driverCfg, err := cfg.driverConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if driverCfg.Addr != "db-1.internal:3306" {
+		t.Errorf("expected first host as initial address, got %s", driverCfg.Addr)
+	}
+
+	cfg.Host = ""
+	cfg.Hosts = []string{"db-1.internal:0"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected invalid host port to be rejected")
+	}
+}
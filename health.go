@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Health is the result of a health check.
+type Health struct {
+	// Latency is the round trip time of the check query on the primary.
+	Latency time.Duration
+
+	// Version is the server version reported by the primary.
+	Version string
+
+	// ReadOnly reports whether the primary has read_only enabled.
+	ReadOnly bool
+
+	// Replicas holds the replication status of each configured replica.
+	Replicas []ReplicaHealth
+
+	// Breaker is the circuit breaker state.
+	Breaker BreakerState
+}
+
+// ReplicaHealth is the replication status of a replica.
+type ReplicaHealth struct {
+	// Name identifies the replica.
+	Name string
+
+	// Lag is the replica's Seconds_Behind_Source.
+	Lag time.Duration
+
+	// Excluded reports whether reads currently skip the replica because
+	// it lags too far behind.
+	Excluded bool
+
+	// Err is set if the replica could not be reached or is not
+	// replicating.
+	Err error
+}
+
+// Health queries the primary for its version and read_only state and each
+// replica for its replication lag. It bypasses the circuit breaker and lazy
+// connect checks so it can be used as a readiness probe. An error is
+// returned only if the primary cannot be queried.
+func (a *MySQLAdapter) Health(ctx context.Context) (*Health, error) {
+	if a.db == nil {
+		return nil, fmt.Errorf("mysql: adapter not connected")
+	}
+
+	h := &Health{Breaker: a.BreakerState()}
+	start := time.Now()
+	var readOnly int64
+	if err := a.db.QueryRowContext(ctx, "SELECT VERSION(), @@GLOBAL.read_only").Scan(&h.Version, &readOnly); err != nil {
+		return nil, fmt.Errorf("mysql: health check failed: %w", err)
+	}
+	h.Latency = time.Since(start)
+	h.ReadOnly = readOnly != 0
+
+	if a.replicas != nil {
+		for _, r := range a.replicas.replicas {
+			lag, err := replicaLag(ctx, r.db)
+			h.Replicas = append(h.Replicas, ReplicaHealth{
+				Name:     r.name,
+				Lag:      lag,
+				Excluded: r.excluded.Load(),
+				Err:      err,
+			})
+		}
+	}
+	return h, nil
+}
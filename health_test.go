@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+const healthQuery = "SELECT VERSION(), @@GLOBAL.read_only"
+
+func TestMySQLAdapter_Health(t *testing.T) {
+	primary := &scriptConnector{results: map[string]fakeResult{
+		healthQuery: {columns: []string{"VERSION()", "@@GLOBAL.read_only"}, rows: [][]driver.Value{{"8.0.36", int64(1)}}},
+	}}
+	replicaConn := &scriptConnector{results: map[string]fakeResult{
+		"SHOW REPLICA STATUS": {
+			columns: []string{"Seconds_Behind_Source"},
+			rows:    [][]driver.Value{{"3"}},
+		},
+	}}
+
+	a := NewMySQLAdapter()
+	if _, err := a.Health(context.Background()); err == nil {
+		t.Error("expected error before Connect")
+	}
+
+	a.db = sql.OpenDB(primary)
+	a.replicas = &replicaSet{replicas: []*replica{{name: "r1", db: sql.OpenDB(replicaConn)}}}
+	defer func() {
+		a.replicas.close()
+		_ = a.db.Close()
+	}()
+
+	h, err := a.Health(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.Version != "8.0.36" || !h.ReadOnly || h.Latency <= 0 {
+		t.Errorf("unexpected primary health %+v", h)
+	}
+	if len(h.Replicas) != 1 || h.Replicas[0].Name != "r1" || h.Replicas[0].Lag != 3*time.Second || h.Replicas[0].Err != nil {
+		t.Errorf("unexpected replica health %+v", h.Replicas)
+	}
+
+	primary.mu.Lock()
+	primary.results[healthQuery] = fakeResult{err: errors.New("gone away")}
+	primary.mu.Unlock()
+	if _, err := a.Health(context.Background()); err == nil {
+		t.Error("expected error when the primary cannot be queried")
+	}
+}
@@ -0,0 +1,403 @@
+package mysql
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// IDGenerator produces client-side identifiers for generated fields.
+// A generator is selected by the type hint of a generated field mapping:
+//
+//	generated:
+//	  - object: ID
+//	    data: id
+//	    type: uuidv7
+//
+// Appending BinarySuffix to the hint (uuidv7_bin) stores the identifier as
+// BINARY(16); the generator must then also implement BinaryCodec.
+type IDGenerator interface {
+	// NewID returns a new identifier in the form stored on the object.
+	NewID() (interface{}, error)
+}
+
+// BinaryCodec converts identifiers between their object form and the
+// 16-byte form stored in BINARY(16) columns.
+type BinaryCodec interface {
+	EncodeBinary(id interface{}) ([]byte, error)
+	DecodeBinary(b []byte) (interface{}, error)
+}
+
+// BinarySuffix marks a type hint whose identifier is stored as BINARY(16).
+const BinarySuffix = "_bin"
+
+// Built-in generator names.
+const (
+	GeneratorUUIDv7    = "uuidv7"
+	GeneratorULID      = "ulid"
+	GeneratorSnowflake = "snowflake"
+)
+
+// RegisterIDGenerator makes gen available to generated fields whose type hint is name.
+// Registering a generator under an existing name replaces it.
+func (a *MySQLAdapter) RegisterIDGenerator(name string, gen IDGenerator) {
+	if a.generators == nil {
+		a.generators = make(map[string]IDGenerator)
+	}
+	a.generators[name] = gen
+}
+
+// generatorFor resolves a type hint to its generator, whether the
+// identifier is stored in binary form, and whether a generator was found.
+func (a *MySQLAdapter) generatorFor(typeHint string) (gen IDGenerator, binary bool, ok bool) {
+	name, binary := strings.CutSuffix(typeHint, BinarySuffix)
+	gen, ok = a.generators[name]
+	if !ok {
+		return nil, false, false
+	}
+	return gen, binary, true
+}
+
+// isClientGenerated reports whether a generated field is filled by the adapter.
+func (a *MySQLAdapter) isClientGenerated(field adapter.PropertyMapping) bool {
+	_, _, ok := a.generatorFor(field.Type)
+	return ok
+}
+
+// assignClientIDs fills the operation's client-generated fields on data and
+// returns their columns and database values. Values already set on the
+// object are kept.
+func (a *MySQLAdapter) assignClientIDs(op *adapter.Operation, data map[string]interface{}) ([]string, []interface{}, error) {
+	var fields []string
+	var values []interface{}
+
+	for _, gen := range op.Generated {
+		if !a.isClientGenerated(gen) {
+			continue
+		}
+
+		val, ok := data[gen.ObjectField]
+		if !ok || isZeroID(val) {
+			g, _, _ := a.generatorFor(gen.Type)
+			id, err := g.NewID()
+			if err != nil {
+				return nil, nil, fmt.Errorf("mysql: failed to generate %s: %w", gen.ObjectField, err)
+			}
+			data[gen.ObjectField] = id
+			val = id
+		}
+
+		encoded, err := a.encodeID(gen, val)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields = append(fields, gen.DataField)
+		values = append(values, encoded)
+	}
+
+	return fields, values, nil
+}
+
+// encodeID converts an identifier to its database form when its field is
+// stored as BINARY(16).
+func (a *MySQLAdapter) encodeID(field adapter.PropertyMapping, val interface{}) (interface{}, error) {
+	gen, binary, _ := a.generatorFor(field.Type)
+	if !binary {
+		return val, nil
+	}
+	codec, ok := gen.(BinaryCodec)
+	if !ok {
+		return nil, fmt.Errorf("mysql: generator for %s cannot encode binary identifiers", field.Type)
+	}
+	b, err := codec.EncodeBinary(val)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: failed to encode %s: %w", field.ObjectField, err)
+	}
+	return b, nil
+}
+
+// encodeIDs converts values in place for the fields they were taken from.
+func (a *MySQLAdapter) encodeIDs(fields []adapter.PropertyMapping, values []interface{}) error {
+	for i := range values {
+		if i >= len(fields) {
+			break
+		}
+		encoded, err := a.encodeID(fields[i], values[i])
+		if err != nil {
+			return err
+		}
+		values[i] = encoded
+	}
+	return nil
+}
+
+// encodeParams returns params with the values named after a binary
+// identifier field of op, by object or data field, encoded to their stored
+// form. params itself is left untouched.
+func (a *MySQLAdapter) encodeParams(op *adapter.Operation, params map[string]interface{}) (map[string]interface{}, error) {
+	var encoded map[string]interface{}
+	seen := make(map[string]bool)
+	for _, fields := range [][]adapter.PropertyMapping{op.Identifier, op.Properties} {
+		for _, f := range fields {
+			if _, binary, _ := a.generatorFor(f.Type); !binary {
+				continue
+			}
+			for _, name := range []string{f.ObjectField, f.DataField} {
+				val, ok := params[name]
+				if !ok || seen[name] {
+					continue
+				}
+				seen[name] = true
+
+				val, err := a.encodeID(f, val)
+				if err != nil {
+					return nil, err
+				}
+				if encoded == nil {
+					encoded = make(map[string]interface{}, len(params))
+					for k, v := range params {
+						encoded[k] = v
+					}
+				}
+				encoded[name] = val
+			}
+		}
+	}
+	if encoded == nil {
+		return params, nil
+	}
+	return encoded, nil
+}
+
+// decodeIDs converts BINARY(16) identifiers in a result row back to their
+// object form, based on the type hints of the mapped properties.
+func (a *MySQLAdapter) decodeIDs(props []adapter.PropertyMapping, row map[string]interface{}) error {
+	for _, prop := range props {
+		gen, binary, _ := a.generatorFor(prop.Type)
+		if !binary {
+			continue
+		}
+		b, ok := row[prop.DataField].([]byte)
+		if !ok {
+			continue
+		}
+		codec, ok := gen.(BinaryCodec)
+		if !ok {
+			return fmt.Errorf("mysql: generator for %s cannot decode binary identifiers", prop.Type)
+		}
+		id, err := codec.DecodeBinary(b)
+		if err != nil {
+			return fmt.Errorf("mysql: failed to decode %s: %w", prop.DataField, err)
+		}
+		row[prop.DataField] = id
+	}
+	return nil
+}
+
+// isZeroID reports whether val is an unset identifier.
+func isZeroID(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case int64:
+		return v == 0
+	case int:
+		return v == 0
+	case []byte:
+		return len(v) == 0
+	}
+	return false
+}
+
+// UUIDv7Generator generates time-ordered RFC 9562 version 7 UUIDs in their
+// canonical 36-character form.
+type UUIDv7Generator struct{}
+
+// NewID returns a new UUIDv7 string.
+func (UUIDv7Generator) NewID() (interface{}, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+	putMillis48(u[:6], time.Now())
+	u[6] = (u[6] & 0x0f) | 0x70 // version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return formatUUID(u[:]), nil
+}
+
+// EncodeBinary converts a UUID string to its 16 bytes.
+func (UUIDv7Generator) EncodeBinary(id interface{}) ([]byte, error) {
+	switch v := id.(type) {
+	case []byte:
+		if len(v) != 16 {
+			return nil, fmt.Errorf("invalid UUID length %d", len(v))
+		}
+		return v, nil
+	case string:
+		b, err := hex.DecodeString(strings.ReplaceAll(v, "-", ""))
+		if err != nil || len(b) != 16 {
+			return nil, fmt.Errorf("invalid UUID %q", v)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported UUID type %T", id)
+}
+
+// DecodeBinary converts 16 bytes to a UUID string.
+func (UUIDv7Generator) DecodeBinary(b []byte) (interface{}, error) {
+	if len(b) != 16 {
+		return nil, fmt.Errorf("invalid UUID length %d", len(b))
+	}
+	return formatUUID(b), nil
+}
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// ULIDGenerator generates lexicographically sortable identifiers in their
+// 26-character Crockford base32 form.
+type ULIDGenerator struct{}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewID returns a new ULID string.
+func (ULIDGenerator) NewID() (interface{}, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+	putMillis48(u[:6], time.Now())
+	return encodeULID(u[:]), nil
+}
+
+// EncodeBinary converts a ULID string to its 16 bytes.
+func (ULIDGenerator) EncodeBinary(id interface{}) ([]byte, error) {
+	switch v := id.(type) {
+	case []byte:
+		if len(v) != 16 {
+			return nil, fmt.Errorf("invalid ULID length %d", len(v))
+		}
+		return v, nil
+	case string:
+		return decodeULID(v)
+	}
+	return nil, fmt.Errorf("unsupported ULID type %T", id)
+}
+
+// DecodeBinary converts 16 bytes to a ULID string.
+func (ULIDGenerator) DecodeBinary(b []byte) (interface{}, error) {
+	if len(b) != 16 {
+		return nil, fmt.Errorf("invalid ULID length %d", len(b))
+	}
+	return encodeULID(b), nil
+}
+
+// encodeULID renders 128 bits as 26 base32 characters, most significant first.
+func encodeULID(b []byte) string {
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+func decodeULID(s string) ([]byte, error) {
+	if len(s) != 26 || s[0] > '7' {
+		return nil, fmt.Errorf("invalid ULID %q", s)
+	}
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(crockford, upper(s[i]))
+		if v < 0 {
+			return nil, fmt.Errorf("invalid ULID %q", s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], lo)
+	return b, nil
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func putMillis48(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// SnowflakeEpoch is the epoch, in Unix milliseconds, of snowflake identifiers.
+const SnowflakeEpoch int64 = 1288834974657
+
+// MaxSnowflakeNode is the largest node ID a SnowflakeGenerator accepts.
+const MaxSnowflakeNode = 1<<10 - 1
+
+// SnowflakeGenerator generates 64-bit identifiers composed of a 41-bit
+// millisecond timestamp, a 10-bit node ID and a 12-bit sequence.
+// It is safe for concurrent use.
+type SnowflakeGenerator struct {
+	mu       sync.Mutex
+	node     int64
+	lastMS   int64
+	sequence int64
+	now      func() time.Time
+}
+
+// NewSnowflakeGenerator creates a snowflake generator for the given node ID.
+// Every process writing to the same table must use a distinct node ID.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("mysql: snowflake node ID must be between 0 and %d, got %d", MaxSnowflakeNode, node)
+	}
+	return &SnowflakeGenerator{node: node, now: time.Now}, nil
+}
+
+// NewID returns a new snowflake identifier as an int64.
+func (g *SnowflakeGenerator) NewID() (interface{}, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().UnixMilli() - SnowflakeEpoch
+	if ms < g.lastMS {
+		// Clock moved backwards; keep issuing from the last timestamp
+		ms = g.lastMS
+	}
+	if ms == g.lastMS {
+		g.sequence = (g.sequence + 1) & 0xfff
+		if g.sequence == 0 {
+			// Sequence exhausted for this millisecond; wait for the next one
+			for ms <= g.lastMS {
+				time.Sleep(100 * time.Microsecond)
+				ms = g.now().UnixMilli() - SnowflakeEpoch
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMS = ms
+
+	return ms<<22 | g.node<<12 | g.sequence, nil
+}
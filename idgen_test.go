@@ -0,0 +1,250 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestUUIDv7Generator(t *testing.T) {
+	g := UUIDv7Generator{}
+
+	id, err := g.NewID()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, ok := id.(string)
+	if !ok {
+		t.Fatalf("expected string, got %T", id)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(s) {
+		t.Errorf("'%s' is not a version 7 UUID", s)
+	}
+
+	b, err := g.EncodeBinary(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b) != 16 {
+		t.Fatalf("expected 16 bytes, got %d", len(b))
+	}
+	decoded, err := g.DecodeBinary(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded != s {
+		t.Errorf("expected round trip to '%s', got '%v'", s, decoded)
+	}
+
+	if _, err := g.EncodeBinary("not-a-uuid"); err == nil {
+		t.Error("expected error for invalid UUID")
+	}
+}
+
+func TestULIDGenerator(t *testing.T) {
+	g := ULIDGenerator{}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := g.NewID()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, id.(string))
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	for _, id := range ids {
+		if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(id) {
+			t.Errorf("'%s' is not a ULID", id)
+		}
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("expected ULIDs to sort by creation time, got %v", ids)
+	}
+
+	b, err := g.EncodeBinary(ids[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := g.DecodeBinary(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded != ids[0] {
+		t.Errorf("expected round trip to '%s', got '%v'", ids[0], decoded)
+	}
+
+	known := "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	b, err = g.EncodeBinary(known)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(b[:6], []byte{0x01, 0x56, 0x3e, 0x3a, 0xb5, 0xd3}) {
+		t.Errorf("unexpected timestamp bytes %x", b[:6])
+	}
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	if _, err := NewSnowflakeGenerator(MaxSnowflakeNode + 1); err == nil {
+		t.Error("expected error for out of range node ID")
+	}
+
+	g, err := NewSnowflakeGenerator(5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fixed := time.UnixMilli(SnowflakeEpoch + 1000)
+	g.now = func() time.Time { return fixed }
+
+	first, _ := g.NewID()
+	second, _ := g.NewID()
+
+	a, b := first.(int64), second.(int64)
+	if a>>22 != 1000 {
+		t.Errorf("expected timestamp 1000, got %d", a>>22)
+	}
+	if (a>>12)&MaxSnowflakeNode != 5 {
+		t.Errorf("expected node 5, got %d", (a>>12)&MaxSnowflakeNode)
+	}
+	if b != a+1 {
+		t.Errorf("expected sequence to advance within a millisecond, got %d then %d", a, b)
+	}
+}
+
+func TestAssignClientIDs(t *testing.T) {
+	a := NewMySQLAdapter()
+	op := &adapter.Operation{
+		Generated: []adapter.PropertyMapping{
+			{ObjectField: "ID", DataField: "id", Type: "uuidv7_bin"},
+			{ObjectField: "CreatedAt", DataField: "created_at"},
+		},
+	}
+
+	data := map[string]interface{}{}
+	fields, values, err := a.assignClientIDs(op, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fields) != 1 || fields[0] != "id" {
+		t.Fatalf("expected only the id column, got %v", fields)
+	}
+	if _, ok := data["ID"].(string); !ok {
+		t.Errorf("expected object to receive UUID string, got %T", data["ID"])
+	}
+	if b, ok := values[0].([]byte); !ok || len(b) != 16 {
+		t.Errorf("expected BINARY(16) value, got %v", values[0])
+	}
+
+	// Identifiers already present on the object are kept
+	preset := map[string]interface{}{"ID": "01890a5d-ac96-774b-bcce-b302099a8057"}
+	if _, _, err := a.assignClientIDs(op, preset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preset["ID"] != "01890a5d-ac96-774b-bcce-b302099a8057" {
+		t.Errorf("expected preset ID to be kept, got %v", preset["ID"])
+	}
+}
+
+func TestDecodeIDs(t *testing.T) {
+	a := NewMySQLAdapter()
+	props := []adapter.PropertyMapping{
+		{ObjectField: "ID", DataField: "id", Type: "uuidv7_bin"},
+		{ObjectField: "Name", DataField: "name"},
+	}
+	raw := []byte{0x01, 0x89, 0x0a, 0x5d, 0xac, 0x96, 0x77, 0x4b, 0xbc, 0xce, 0xb3, 0x02, 0x09, 0x9a, 0x80, 0x57}
+	row := map[string]interface{}{"id": raw, "name": []byte("Ada")}
+
+	if err := a.decodeIDs(props, row); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row["id"] != "01890a5d-ac96-774b-bcce-b302099a8057" {
+		t.Errorf("unexpected decoded ID %v", row["id"])
+	}
+	if _, ok := row["name"].([]byte); !ok {
+		t.Errorf("expected untyped column to be left alone, got %T", row["name"])
+	}
+}
+
+func TestMySQLAdapter_InsertClientIDs(t *testing.T) {
+	tests := []struct {
+		typeHint string
+		valid    func(id interface{}) bool
+	}{
+		{GeneratorUUIDv7, func(id interface{}) bool { s, _ := id.(string); return len(s) == 36 }},
+		{GeneratorULID, func(id interface{}) bool { s, _ := id.(string); return len(s) == 26 }},
+		{GeneratorSnowflake, func(id interface{}) bool { n, _ := id.(int64); return n > 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeHint, func(t *testing.T) {
+			// The ID column is part of the statement and LastInsertId is ignored
+			script := &scriptConnector{results: map[string]fakeResult{
+				"INSERT INTO orders (total, id) VALUES (?, ?)": {exec: fakeExec{affected: 1}},
+			}}
+			a := newScriptAdapter(t, script)
+
+			op := &adapter.Operation{
+				Statement:  "orders",
+				Properties: []adapter.PropertyMapping{{ObjectField: "Total", DataField: "total"}},
+				Generated:  []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id", Type: tt.typeHint}},
+			}
+			obj := map[string]interface{}{"Total": 10}
+			if err := a.Insert(context.Background(), op, []interface{}{obj}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.valid(obj["ID"]) {
+				t.Errorf("expected a generated %s ID, got %v", tt.typeHint, obj["ID"])
+			}
+		})
+	}
+}
+
+func TestMySQLAdapter_FetchBinaryID(t *testing.T) {
+	const id = "01890a5d-ac96-774b-bcce-b302099a8057"
+	raw := []byte{0x01, 0x89, 0x0a, 0x5d, 0xac, 0x96, 0x77, 0x4b, 0xbc, 0xce, 0xb3, 0x02, 0x09, 0x9a, 0x80, 0x57}
+	script := &scriptConnector{results: map[string]fakeResult{
+		"SELECT id, name FROM orders WHERE id = ?": {
+			columns: []string{"id", "name"},
+			rows:    [][]driver.Value{{raw, "Ada"}},
+		},
+	}}
+	var args []interface{}
+	a := newScriptAdapter(t, script, WithInterceptors(func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+		args = call.Args
+		return next(ctx, call)
+	}))
+
+	op := &adapter.Operation{
+		Statement: "SELECT id, name FROM orders WHERE id = {id}",
+		Properties: []adapter.PropertyMapping{
+			{ObjectField: "ID", DataField: "id", Type: "uuidv7_bin"},
+			{ObjectField: "Name", DataField: "name"},
+		},
+	}
+	params := map[string]interface{}{"id": id}
+	rows, err := a.Fetch(context.Background(), op, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(args) != 1 || !bytes.Equal(args[0].([]byte), raw) {
+		t.Errorf("expected the parameter to be sent in binary form, got %v", args)
+	}
+	if params["id"] != id {
+		t.Errorf("expected caller's params to be left alone, got %v", params["id"])
+	}
+	if row, _ := rows[0].(map[string]interface{}); row["id"] != id {
+		t.Errorf("expected the ID to round-trip, got %v", rows[0])
+	}
+
+	// A value that is not an identifier of the type fails before the query
+	if _, err := a.Fetch(context.Background(), op, map[string]interface{}{"id": "not-a-uuid"}); err == nil {
+		t.Error("expected an invalid identifier to be rejected")
+	}
+}
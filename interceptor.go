@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// Call describes a statement an adapter operation is about to run.
+// Interceptors may modify SQL and Args before passing the call on.
+type Call struct {
+	// Kind is the operation kind: fetch, insert, update, delete or action.
+	Kind adapter.OperationType
+
+	// Operation is the mapping operation, or nil for Execute.
+	Operation *adapter.Operation
+
+	// Action is the custom action run by Execute, or nil.
+	Action *adapter.Action
+
+	// SQL is the statement with positional placeholders.
+	SQL string
+
+	// Args are the statement arguments.
+	Args []interface{}
+}
+
+// Result is the outcome of a call.
+type Result struct {
+	// Rows holds the records returned by a fetch or query action, each a
+	// map[string]interface{} of column values.
+	Rows []interface{}
+
+	// RowsAffected is the number of rows a write affected.
+	RowsAffected int64
+
+	// LastInsertID is the auto-increment value generated by an insert.
+	LastInsertID int64
+}
+
+// Handler runs a call.
+type Handler func(ctx context.Context, call *Call) (*Result, error)
+
+// Interceptor wraps every statement run by Fetch, Insert, Update, Delete
+// and Execute. It may modify the call, short-circuit it by returning a
+// result without calling next, or observe the result and error of next.
+// A nil result without an error is treated as an empty result.
+type Interceptor func(ctx context.Context, call *Call, next Handler) (*Result, error)
+
+// Use appends interceptors to the chain. The first interceptor added is the
+// outermost. It must not be called concurrently with operations.
+func (a *MySQLAdapter) Use(interceptors ...Interceptor) {
+	a.config.Interceptors = append(a.config.Interceptors, interceptors...)
+}
+
+// intercept runs call through the interceptor chain, ending with run, which
+// is logged and timed as the statement that actually ran.
+func (a *MySQLAdapter) intercept(ctx context.Context, call *Call, run Handler) (*Result, error) {
+	h := a.logged(a.watchSlow(run))
+	for i := len(a.config.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := a.config.Interceptors[i], h
+		h = func(ctx context.Context, call *Call) (*Result, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	result, err := h(ctx, call)
+	if result == nil && err == nil {
+		result = &Result{}
+	}
+	return result, err
+}
+
+// execCall runs call as a statement without results on q.
+func (a *MySQLAdapter) execCall(ctx context.Context, q queryer, call *Call) (*Result, error) {
+	return a.intercept(ctx, call, func(ctx context.Context, call *Call) (*Result, error) {
+		res, err := q.ExecContext(ctx, call.SQL, call.Args...)
+		if err != nil {
+			return nil, err
+		}
+		return execResult(res), nil
+	})
+}
+
+// queryCall runs call as a query on q, reading its rows with the binary
+// identifiers of props decoded.
+func (a *MySQLAdapter) queryCall(ctx context.Context, q queryer, call *Call, props []adapter.PropertyMapping) (*Result, error) {
+	return a.intercept(ctx, call, func(ctx context.Context, call *Call) (*Result, error) {
+		rows, err := q.QueryContext(ctx, call.SQL, call.Args...)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rows.Close() }()
+
+		results, err := a.scanRows(rows, props)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Rows: results}, nil
+	})
+}
+
+// execResult converts a driver result. The MySQL driver reports both values
+// without error.
+func execResult(res sql.Result) *Result {
+	affected, _ := res.RowsAffected()
+	lastID, _ := res.LastInsertId()
+	return &Result{RowsAffected: affected, LastInsertID: lastID}
+}
@@ -0,0 +1,229 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestMySQLAdapter_InterceptorChain(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"DELETE FROM users WHERE id = ? AND tenant_id = ?": {},
+	}}
+	var order []string
+	var seen *Call
+
+	a := NewMySQLAdapter()
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+	a.Use(
+		func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+			order = append(order, "outer")
+			res, err := next(ctx, call)
+			order = append(order, "outer done")
+			return res, err
+		},
+		func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+			order = append(order, "tenant")
+			call.SQL += " AND tenant_id = ?"
+			call.Args = append(call.Args, 7)
+			seen = call
+			return next(ctx, call)
+		},
+	)
+
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+	}
+	if err := a.Delete(context.Background(), op, []interface{}{1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := script.executed(); len(got) != 1 || got[0] != "DELETE FROM users WHERE id = ? AND tenant_id = ?" {
+		t.Errorf("expected modified statement, got %v", got)
+	}
+	if len(order) != 3 || order[0] != "outer" || order[1] != "tenant" || order[2] != "outer done" {
+		t.Errorf("unexpected order %v", order)
+	}
+	if seen.Kind != adapter.OpDelete || seen.Operation != op || seen.Action != nil || len(seen.Args) != 2 {
+		t.Errorf("unexpected call %+v", seen)
+	}
+}
+
+func TestMySQLAdapter_InterceptorShortCircuit(t *testing.T) {
+	dialer := &countingConnector{}
+	cached := []interface{}{map[string]interface{}{"id": 1}}
+
+	a, err := NewMySQLAdapterWithOptions(WithInterceptors(func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+		if call.Kind == adapter.OpFetch {
+			return &Result{Rows: cached}, nil
+		}
+		return next(ctx, call)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(dialer)
+	defer func() { _ = a.db.Close() }()
+
+	results, err := a.Fetch(context.Background(), &adapter.Operation{Statement: "SELECT * FROM users"}, nil)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected cached results, got %v (%v)", results, err)
+	}
+	if dialer.attempts != 0 {
+		t.Error("expected no database access")
+	}
+
+	// An empty short-circuited result is still not found
+	cached = nil
+	if _, err := a.Fetch(context.Background(), &adapter.Operation{Statement: "SELECT * FROM users"}, nil); !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMySQLAdapter_InterceptorObserve(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"SELECT id FROM users": {columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}, {int64(2)}}},
+	}}
+	var calls []*Call
+	var results []*Result
+	var errs []error
+
+	a := NewMySQLAdapter()
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+	a.Use(func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+		res, err := next(ctx, call)
+		calls = append(calls, call)
+		results = append(results, res)
+		errs = append(errs, err)
+		return res, err
+	})
+
+	query := &adapter.Action{Name: "ids", Statement: "SELECT id FROM users", Result: &adapter.ResultMapping{}}
+	if _, err := a.Execute(context.Background(), query, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec := &adapter.Action{Name: "purge", Statement: "DELETE FROM sessions"}
+	if _, err := a.Execute(context.Background(), exec, nil); err == nil {
+		t.Fatal("expected error for unscripted statement")
+	}
+
+	if len(calls) != 2 || calls[0].Kind != adapter.OpAction || calls[0].Action != query || calls[1].Action != exec {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+	if len(results[0].Rows) != 2 || errs[0] != nil {
+		t.Errorf("expected two rows, got %+v (%v)", results[0], errs[0])
+	}
+	if results[1] != nil || errs[1] == nil {
+		t.Errorf("expected the statement error, got %+v (%v)", results[1], errs[1])
+	}
+}
+
+// scopeTenant is an interceptor restricting every statement to tenant 7.
+func scopeTenant(ctx context.Context, call *Call, next Handler) (*Result, error) {
+	call.SQL = strings.Replace(call.SQL, " WHERE ", " WHERE tenant_id = ? AND ", 1)
+	call.Args = append([]interface{}{7}, call.Args...)
+	return next(ctx, call)
+}
+
+func TestMySQLAdapter_InterceptorNilResult(t *testing.T) {
+	script := &scriptConnector{}
+	a := newScriptAdapter(t, script, WithInterceptors(func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+		return nil, nil
+	}))
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+	}
+	ctx := context.Background()
+
+	// A short-circuited call without a result has no rows and affects none
+	if _, err := a.Fetch(ctx, &adapter.Operation{Statement: "SELECT * FROM users"}, nil); !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound from Fetch, got %v", err)
+	}
+	if rows, err := a.Fetch(ctx, &adapter.Operation{Statement: "SELECT * FROM users", Multi: true}, nil); err != nil || len(rows) != 0 {
+		t.Errorf("expected no rows from a multi fetch, got %v, %v", rows, err)
+	}
+	if err := a.Update(ctx, op, []interface{}{map[string]interface{}{"ID": 1, "Name": "Ann"}}); !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound from Update, got %v", err)
+	}
+	if err := a.Delete(ctx, op, []interface{}{1}); !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound from Delete, got %v", err)
+	}
+	if err := a.Insert(ctx, op, []interface{}{map[string]interface{}{"Name": "Ann"}}); err != nil {
+		t.Errorf("unexpected error from Insert: %v", err)
+	}
+	for _, q := range script.executed() {
+		if q != "ROLLBACK" {
+			t.Errorf("expected no statement to reach the database, got %q", q)
+		}
+	}
+}
+
+func TestMySQLAdapter_InterceptedProbe(t *testing.T) {
+	// The row exists for another tenant only, so it is not found in scope
+	script := &scriptConnector{results: map[string]fakeResult{
+		"UPDATE users SET name = ?, version = version + 1 WHERE tenant_id = ? AND id = ? AND version = ?": {exec: fakeExec{}},
+		"SELECT COUNT(*) AS matched FROM users WHERE tenant_id = ? AND id = ? AND version = ?":            {columns: []string{"matched"}, rows: [][]driver.Value{{int64(0)}}},
+		"SELECT 1 FROM users WHERE tenant_id = ? AND id = ? LIMIT 1":                                      {columns: []string{"1"}},
+	}}
+	a := newScriptAdapter(t, script, WithInterceptors(scopeTenant))
+
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+		Condition:  []adapter.PropertyMapping{{ObjectField: "Version", DataField: "version"}},
+	}
+	err := a.Update(context.Background(), op, []interface{}{map[string]interface{}{"ID": 1, "Name": "Ann", "Version": 3}})
+	if !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound within the tenant, got %v", err)
+	}
+}
+
+func TestMySQLAdapter_InterceptedRefetch(t *testing.T) {
+	var refetched *Call
+	script := &scriptConnector{results: map[string]fakeResult{
+		"INSERT INTO orders (total, id) VALUES (?, ?)": {exec: fakeExec{affected: 1}},
+		"SELECT created_at FROM orders WHERE tenant_id = ? AND id = ? LIMIT 1": {
+			columns: []string{"created_at"},
+			rows:    [][]driver.Value{{"2026-01-02 03:04:05"}},
+		},
+	}}
+	a := newScriptAdapter(t, script, WithRefetchGenerated(), WithInterceptors(scopeTenant, func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+		if call.Kind == adapter.OpFetch {
+			refetched = call
+		}
+		return next(ctx, call)
+	}))
+
+	op := &adapter.Operation{
+		Statement:  "orders",
+		Properties: []adapter.PropertyMapping{{ObjectField: "Total", DataField: "total"}},
+		Generated: []adapter.PropertyMapping{
+			{ObjectField: "CreatedAt", DataField: "created_at"},
+			{ObjectField: "ID", DataField: "id", Type: "uuidv7_bin"},
+		},
+	}
+	obj := map[string]interface{}{"Total": 10}
+	if err := a.Insert(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj["CreatedAt"] != "2026-01-02 03:04:05" {
+		t.Errorf("expected created_at to be re-fetched, got %v", obj["CreatedAt"])
+	}
+	if refetched == nil || refetched.Operation != op || len(refetched.Args) != 2 {
+		t.Fatalf("expected the re-fetch to be intercepted, got %+v", refetched)
+	}
+	if b, ok := refetched.Args[1].([]byte); !ok || len(b) != 16 {
+		t.Errorf("expected the row to be located by its binary ID, got %v", refetched.Args[1])
+	}
+}
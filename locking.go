@@ -0,0 +1,89 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// ErrConcurrentModification is returned by Update when the record exists but
+// its optimistic locking condition no longer matches, meaning another writer
+// modified it since it was read. It matches adapter.ErrConflict with errors.Is.
+var ErrConcurrentModification = &adapter.AdapterError{
+	Code:    "CONFLICT",
+	Message: "concurrent modification: version mismatch",
+	Cause:   adapter.ErrConflict,
+}
+
+// lockVersion is an optimistic locking condition resolved against an object.
+type lockVersion struct {
+	field   adapter.PropertyMapping
+	current interface{}
+	// next is the value the version takes after a successful update, or nil
+	// when the condition is not an integer and is only compared.
+	next interface{}
+}
+
+// lockVersions resolves the operation's condition fields against data.
+// Conditions missing from data are skipped.
+func lockVersions(op *adapter.Operation, data map[string]interface{}) ([]lockVersion, error) {
+	var versions []lockVersion
+	for _, cond := range op.Condition {
+		val, ok := data[cond.ObjectField]
+		if !ok {
+			continue
+		}
+		next, err := nextVersion(val)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: condition field %s: %w", cond.ObjectField, err)
+		}
+		versions = append(versions, lockVersion{field: cond, current: val, next: next})
+	}
+	return versions, nil
+}
+
+// isConditionField reports whether dataField is one of the operation's conditions.
+func isConditionField(op *adapter.Operation, dataField string) bool {
+	for _, cond := range op.Condition {
+		if cond.DataField == dataField {
+			return true
+		}
+	}
+	return false
+}
+
+// nextVersion returns v incremented by one, keeping its integer type.
+// Non-integer values (timestamps, hashes) return nil.
+func nextVersion(v interface{}) (interface{}, error) {
+	switch n := v.(type) {
+	case int:
+		return n + 1, nil
+	case int8:
+		return n + 1, nil
+	case int16:
+		return n + 1, nil
+	case int32:
+		return n + 1, nil
+	case int64:
+		return n + 1, nil
+	case uint:
+		return n + 1, nil
+	case uint8:
+		return n + 1, nil
+	case uint16:
+		return n + 1, nil
+	case uint32:
+		return n + 1, nil
+	case uint64:
+		return n + 1, nil
+	case float64:
+		// Numbers decoded from JSON arrive as float64
+		if n != float64(int64(n)) {
+			return nil, fmt.Errorf("version %v is not a whole number", n)
+		}
+		return n + 1, nil
+	case nil:
+		return nil, fmt.Errorf("version is nil")
+	}
+	return nil, nil
+}
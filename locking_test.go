@@ -0,0 +1,151 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+		wantErr  bool
+	}{
+		{name: "int", value: 1, expected: 2},
+		{name: "int64", value: int64(41), expected: int64(42)},
+		{name: "uint32", value: uint32(9), expected: uint32(10)},
+		{name: "whole float64", value: float64(3), expected: float64(4)},
+		{name: "fractional float64", value: 3.5, wantErr: true},
+		{name: "nil", value: nil, wantErr: true},
+		{name: "timestamp", value: time.Unix(0, 0), expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextVersion(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %v (%T), got %v (%T)", tt.expected, tt.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestLockVersions(t *testing.T) {
+	op := &adapter.Operation{
+		Condition: []adapter.PropertyMapping{
+			{ObjectField: "Version", DataField: "version"},
+			{ObjectField: "Revision", DataField: "revision"},
+		},
+	}
+
+	versions, err := lockVersions(op, map[string]interface{}{"Version": int64(5)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("expected 1 version, got %d", len(versions))
+	}
+	if versions[0].field.DataField != "version" || versions[0].current != int64(5) || versions[0].next != int64(6) {
+		t.Errorf("unexpected version %+v", versions[0])
+	}
+
+	if _, err := lockVersions(op, map[string]interface{}{"Version": nil}); err == nil {
+		t.Error("expected error for nil version")
+	}
+}
+
+func TestErrConcurrentModification(t *testing.T) {
+	if !errors.Is(ErrConcurrentModification, ErrConcurrentModification) {
+		t.Error("expected ErrConcurrentModification to match itself")
+	}
+	if !errors.Is(ErrConcurrentModification, adapter.ErrConflict) {
+		t.Error("expected ErrConcurrentModification to match ErrConflict")
+	}
+	if errors.Is(ErrConcurrentModification, adapter.ErrNotFound) {
+		t.Error("expected ErrConcurrentModification to differ from ErrNotFound")
+	}
+	if ErrConcurrentModification.Code != adapter.ErrConflict.Code {
+		t.Errorf("expected code %s, got %s", adapter.ErrConflict.Code, ErrConcurrentModification.Code)
+	}
+}
+
+func TestMySQLAdapter_UpdateVersion(t *testing.T) {
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+		Condition:  []adapter.PropertyMapping{{ObjectField: "Version", DataField: "version"}},
+	}
+	update := "UPDATE users SET name = ?, version = version + 1 WHERE id = ? AND version = ?"
+	count := "SELECT COUNT(*) AS matched FROM users WHERE id = ? AND version = ?"
+	probe := "SELECT 1 FROM users WHERE id = ? LIMIT 1"
+	script := &scriptConnector{results: map[string]fakeResult{
+		update: {exec: fakeExec{affected: 1}},
+	}}
+	a := newScriptAdapter(t, script)
+
+	obj := map[string]interface{}{"ID": 1, "Name": "Ann", "Version": int64(3)}
+	if err := a.Update(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj["Version"] != int64(4) {
+		t.Errorf("expected version 4 written back, got %v", obj["Version"])
+	}
+
+	// The row exists but holds another version
+	script.results[update] = fakeResult{exec: fakeExec{}}
+	script.results[count] = fakeResult{columns: []string{"matched"}, rows: [][]driver.Value{{int64(0)}}}
+	script.results[probe] = fakeResult{columns: []string{"1"}, rows: [][]driver.Value{{int64(1)}}}
+	obj = map[string]interface{}{"ID": 1, "Name": "Ann", "Version": int64(3)}
+	err := a.Update(context.Background(), op, []interface{}{obj})
+	if !errors.Is(err, ErrConcurrentModification) || !errors.Is(err, adapter.ErrConflict) {
+		t.Errorf("expected ErrConcurrentModification, got %v", err)
+	}
+	if obj["Version"] != int64(3) {
+		t.Errorf("expected version to be left alone on conflict, got %v", obj["Version"])
+	}
+
+	// The row is gone
+	script.results[probe] = fakeResult{columns: []string{"1"}}
+	if err := a.Update(context.Background(), op, []interface{}{obj}); !errors.Is(err, adapter.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMySQLAdapter_UpdateComparedCondition(t *testing.T) {
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+		Condition:  []adapter.PropertyMapping{{ObjectField: "Hash", DataField: "hash"}},
+	}
+	// An unchanged row still matches the condition, so no conflict is reported
+	script := &scriptConnector{results: map[string]fakeResult{
+		"UPDATE users SET name = ? WHERE id = ? AND hash = ?":             {exec: fakeExec{}},
+		"SELECT COUNT(*) AS matched FROM users WHERE id = ? AND hash = ?": {columns: []string{"matched"}, rows: [][]driver.Value{{int64(1)}}},
+	}}
+	a := newScriptAdapter(t, script)
+
+	obj := map[string]interface{}{"ID": 1, "Name": "Ann", "Hash": "abc"}
+	if err := a.Update(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("expected no-op update to succeed, got %v", err)
+	}
+	if obj["Hash"] != "abc" {
+		t.Errorf("expected compared condition to be left alone, got %v", obj["Hash"])
+	}
+}
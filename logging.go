@@ -0,0 +1,288 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// redacted replaces parameter values that are not shown or masked.
+const redacted = "[REDACTED]"
+
+// Mask converts a parameter value to the form it is logged in.
+type Mask func(v interface{}) string
+
+// MaskEmail keeps the first character of the local part and the domain of
+// an email address: "j***@example.com".
+func MaskEmail(v interface{}) string {
+	s := fmt.Sprint(v)
+	at := strings.LastIndexByte(s, '@')
+	if at < 1 {
+		return redacted
+	}
+	return s[:1] + "***" + s[at:]
+}
+
+// MaskLast returns a mask keeping only the last n characters of a value.
+func MaskLast(n int) Mask {
+	return func(v interface{}) string {
+		r := []rune(fmt.Sprint(v))
+		if len(r) <= n {
+			return strings.Repeat("*", len(r))
+		}
+		return strings.Repeat("*", len(r)-n) + string(r[len(r)-n:])
+	}
+}
+
+// LogConfig configures statement logging.
+type LogConfig struct {
+	// Logger receives a record for every statement run by an operation.
+	// When nil, statements are logged to slog.Default() if Level is set and
+	// not logged at all otherwise.
+	Logger *slog.Logger
+
+	// Level is the level of statements that succeed. Defaults to debug.
+	Level slog.Leveler
+
+	// ErrorLevel is the level of statements that fail. Defaults to error.
+	ErrorLevel slog.Leveler
+
+	// ShowColumns lists the columns whose values are logged as they are.
+	// Every other value is redacted.
+	ShowColumns []string
+
+	// MaskColumns masks the values of columns, such as MaskEmail for email
+	// addresses.
+	MaskColumns map[string]Mask
+}
+
+// enabled reports whether statements are logged.
+func (c LogConfig) enabled() bool {
+	return c.Logger != nil || c.Level != nil
+}
+
+// logger returns the logger statements are written to.
+func (c LogConfig) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return slog.Default()
+}
+
+// value returns how the value bound to column is logged.
+func (c LogConfig) value(column string, v interface{}) interface{} {
+	if column == "" {
+		return redacted
+	}
+	for _, show := range c.ShowColumns {
+		if strings.EqualFold(show, column) {
+			return v
+		}
+	}
+	for name, mask := range c.MaskColumns {
+		if strings.EqualFold(name, column) {
+			if v == nil {
+				return nil
+			}
+			return mask(v)
+		}
+	}
+	return redacted
+}
+
+// logged wraps run so every call it runs is logged.
+func (a *MySQLAdapter) logged(run Handler) Handler {
+	cfg := a.config.Log
+	if !cfg.enabled() {
+		return run
+	}
+	return func(ctx context.Context, call *Call) (*Result, error) {
+		start := time.Now()
+		res, err := run(ctx, call)
+		cfg.log(ctx, call, time.Since(start), res, err)
+		return res, err
+	}
+}
+
+// log writes the record of a call.
+func (c LogConfig) log(ctx context.Context, call *Call, d time.Duration, res *Result, err error) {
+	level := slog.LevelDebug
+	if c.Level != nil {
+		level = c.Level.Level()
+	}
+	if err != nil {
+		level = slog.LevelError
+		if c.ErrorLevel != nil {
+			level = c.ErrorLevel.Level()
+		}
+	}
+	logger := c.logger()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	columns := argColumns(call.SQL)
+	args := make([]interface{}, len(call.Args))
+	for i, v := range call.Args {
+		var column string
+		if i < len(columns) {
+			column = columns[i]
+		}
+		args[i] = c.value(column, v)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("kind", string(call.Kind)),
+		slog.String("sql", call.SQL),
+		slog.Any("args", args),
+		slog.Duration("duration", d),
+	}
+	if call.Action != nil {
+		attrs = append(attrs, slog.String("action", call.Action.Name))
+	}
+	if res != nil {
+		if res.Rows != nil || call.Kind == adapter.OpFetch {
+			attrs = append(attrs, slog.Int("rows", len(res.Rows)))
+		} else {
+			attrs = append(attrs, slog.Int64("rows_affected", res.RowsAffected))
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "mysql statement", attrs...)
+}
+
+// argColumns returns the column each placeholder of query binds to, or ""
+// where it cannot be told. Placeholders are attributed to the column they
+// are compared with or assigned to (col = ?), or to their position in the
+// column list of an INSERT.
+func argColumns(query string) []string {
+	tokens := sqlTokens(query)
+	var columns []string
+
+	// Columns listed by INSERT INTO t (a, b) and their tuple positions
+	var insertCols []string
+	inValues, depth, pos := false, 0, 0
+	if len(tokens) > 0 && (strings.EqualFold(tokens[0], "INSERT") || strings.EqualFold(tokens[0], "REPLACE")) {
+		for i, t := range tokens {
+			if t == "(" {
+				for j := i + 1; j < len(tokens) && tokens[j] != ")"; j++ {
+					if tokens[j] != "," {
+						insertCols = append(insertCols, unqualified(tokens[j]))
+					}
+				}
+				break
+			}
+			if strings.EqualFold(t, "VALUES") || strings.EqualFold(t, "SELECT") {
+				break
+			}
+		}
+	}
+
+	for i, t := range tokens {
+		switch {
+		case strings.EqualFold(t, "VALUES") || strings.EqualFold(t, "VALUE"):
+			inValues = insertCols != nil
+		case inValues && t == "(":
+			depth++
+			if depth == 1 {
+				pos = 0
+			}
+		case inValues && t == ")":
+			depth--
+		case inValues && depth == 1 && t == ",":
+			pos++
+		case inValues && depth == 0 && isWord(t):
+			// ON DUPLICATE KEY UPDATE and the like
+			inValues = false
+		}
+		if t != "?" {
+			continue
+		}
+
+		column := ""
+		switch {
+		case inValues:
+			if depth == 1 && pos < len(insertCols) && (tokens[i-1] == "(" || tokens[i-1] == ",") &&
+				i+1 < len(tokens) && (tokens[i+1] == ")" || tokens[i+1] == ",") {
+				column = insertCols[pos]
+			}
+		case i >= 2 && isComparison(tokens[i-1]) && isWord(tokens[i-2]):
+			column = unqualified(tokens[i-2])
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// sqlTokens splits query into words, quoted identifiers, placeholders,
+// operators and punctuation. String literals are dropped.
+func sqlTokens(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(query) && query[j] != c {
+				if query[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			i = j
+		case c == '`':
+			j := strings.IndexByte(query[i+1:], '`')
+			if j < 0 {
+				return tokens
+			}
+			tokens = append(tokens, query[i:i+j+2])
+			i += j + 1
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.' || query[j] == '`') {
+				j++
+			}
+			tokens = append(tokens, query[i:j])
+			i = j - 1
+		case c == '<' || c == '>' || c == '!':
+			j := i + 1
+			for j < len(query) && (query[j] == '=' || query[j] == '>') {
+				j++
+			}
+			tokens = append(tokens, query[i:j])
+			i = j - 1
+		default:
+			tokens = append(tokens, string(c))
+		}
+	}
+	return tokens
+}
+
+// isWord reports whether token is an identifier or keyword.
+func isWord(token string) bool {
+	return token != "" && (token[0] == '`' || (isIdentByte(token[0]) && !isDigit(token[0])))
+}
+
+// isComparison reports whether token compares or assigns a column.
+func isComparison(token string) bool {
+	switch strings.ToUpper(token) {
+	case "=", "<>", "!=", "<", ">", "<=", ">=", "<=>", "LIKE":
+		return true
+	}
+	return false
+}
+
+// unqualified strips the table qualifier and quotes of a column.
+func unqualified(column string) string {
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.Trim(column, "`")
+}
@@ -0,0 +1,159 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// logRecords decodes the JSON records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMySQLAdapter_Logging(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"INSERT INTO users (name, email, password) VALUES (?, ?, ?)": {},
+	}}
+	var buf bytes.Buffer
+	a, err := NewMySQLAdapterWithOptions(WithLogging(LogConfig{
+		Logger:      slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		ShowColumns: []string{"name"},
+		MaskColumns: map[string]Mask{"EMAIL": MaskEmail},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+
+	op := &adapter.Operation{
+		Statement: "users",
+		Properties: []adapter.PropertyMapping{
+			{ObjectField: "Name", DataField: "name"},
+			{ObjectField: "Email", DataField: "email"},
+			{ObjectField: "Password", DataField: "password"},
+		},
+	}
+	obj := map[string]interface{}{"Name": "Jane", "Email": "jane@example.com", "Password": "hunter2"}
+	if err := a.Insert(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := a.Execute(context.Background(), &adapter.Action{Name: "purge", Statement: "DELETE FROM sessions WHERE token = {token}"}, map[string]interface{}{"token": "s3cret"}); err == nil {
+		t.Fatal("expected error for unscripted statement")
+	}
+
+	if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "s3cret") {
+		t.Fatalf("expected sensitive values to be redacted, got %s", buf.String())
+	}
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("expected a record per statement, got %d", len(records))
+	}
+
+	insert := records[0]
+	if insert["level"] != "DEBUG" || insert["kind"] != "insert" || insert["rows_affected"] != float64(1) {
+		t.Errorf("unexpected insert record %v", insert)
+	}
+	args, _ := insert["args"].([]interface{})
+	if len(args) != 3 || args[0] != "Jane" || args[1] != "j***@example.com" || args[2] != redacted {
+		t.Errorf("unexpected insert args %v", insert["args"])
+	}
+	if _, ok := insert["duration"]; !ok {
+		t.Error("expected duration")
+	}
+
+	failed := records[1]
+	if failed["level"] != "ERROR" || failed["action"] != "purge" || failed["error"] == nil {
+		t.Errorf("unexpected error record %v", failed)
+	}
+}
+
+func TestMySQLAdapter_LoggingDisabled(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{"DELETE FROM users WHERE id = ?": {}}}
+	var buf bytes.Buffer
+	a, err := NewMySQLAdapterWithOptions(WithLogging(LogConfig{
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+
+	op := &adapter.Operation{Statement: "users", Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}}}
+	if err := a.Delete(context.Background(), op, []interface{}{1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected debug records to be dropped, got %s", buf.String())
+	}
+}
+
+func TestConfig_Logging(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"log_level":        "info",
+		"log_error_level":  "warn",
+		"log_show_columns": []interface{}{"id", "status"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Log.enabled() || cfg.Log.Level.Level() != slog.LevelInfo || cfg.Log.ErrorLevel.Level() != slog.LevelWarn {
+		t.Errorf("unexpected log config %+v", cfg.Log)
+	}
+	if len(cfg.Log.ShowColumns) != 2 {
+		t.Errorf("expected two shown columns, got %v", cfg.Log.ShowColumns)
+	}
+
+	if err := cfg.apply(map[string]interface{}{"log_level": "loud"}); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestArgColumns(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT * FROM users WHERE id = ?", []string{"id"}},
+		{"SELECT * FROM users u WHERE u.`email` LIKE ? AND age >= ? LIMIT ?", []string{"email", "age", ""}},
+		{"UPDATE users SET name = ?, version = version + 1 WHERE id = ? AND version = ?", []string{"name", "id", "version"}},
+		{"INSERT INTO users (name, `email`) VALUES (?, ?), (?, ?)", []string{"name", "email", "name", "email"}},
+		{"INSERT INTO users (name, created) VALUES (?, NOW()) ON DUPLICATE KEY UPDATE name = ?", []string{"name", "name"}},
+		{"INSERT INTO users (a, b) VALUES (UPPER(?), ?)", []string{"", "b"}},
+		{"SELECT * FROM users WHERE name = 'x = ?' AND id IN (?, ?)", []string{"", ""}},
+	}
+	for _, tt := range tests {
+		got := argColumns(tt.query)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") || len(got) != len(tt.want) {
+			t.Errorf("argColumns(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	if got := MaskEmail("jane@example.com"); got != "j***@example.com" {
+		t.Errorf("unexpected masked email %q", got)
+	}
+	if got := MaskLast(4)("4111111111111111"); got != "************1111" {
+		t.Errorf("unexpected masked value %q", got)
+	}
+}
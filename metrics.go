@@ -0,0 +1,160 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metrics receives measurements from the adapter. Implementations must be
+// safe for concurrent use. The otelmetrics package provides an
+// OpenTelemetry implementation.
+type Metrics interface {
+	// ObserveOperation is called after every adapter operation, including
+	// operations rejected because the database is unavailable.
+	ObserveOperation(ctx context.Context, o OperationObservation)
+
+	// ObservePool registers stats as the source of a connection pool's
+	// statistics. It is called for the primary pool ("primary") and each
+	// replica pool when the adapter connects. The returned function is
+	// called when the adapter closes.
+	ObservePool(pool string, stats func() sql.DBStats) (unregister func())
+}
+
+// OperationObservation describes a finished adapter operation.
+type OperationObservation struct {
+	// Kind is the operation kind: fetch, insert, update, delete or action.
+	Kind adapter.OperationType
+
+	// Mapping is the table of a fetch, insert, update or delete, or the
+	// name of an action.
+	Mapping string
+
+	// Duration is how long the operation took.
+	Duration time.Duration
+
+	// RowsRead is the number of rows returned.
+	RowsRead int64
+
+	// RowsWritten is the number of rows inserted, changed or deleted.
+	RowsWritten int64
+
+	// ErrorClass is the ErrorClass of the operation's error, or "" if it
+	// succeeded.
+	ErrorClass string
+}
+
+// NoopMetrics discards all measurements. It is used when no Metrics are
+// configured.
+type NoopMetrics struct{}
+
+// ObserveOperation implements Metrics.
+func (NoopMetrics) ObserveOperation(context.Context, OperationObservation) {}
+
+// ObservePool implements Metrics.
+func (NoopMetrics) ObservePool(string, func() sql.DBStats) func() { return func() {} }
+
+// metrics returns the configured metrics, or NoopMetrics.
+func (a *MySQLAdapter) metrics() Metrics {
+	if a.config.Metrics == nil {
+		return NoopMetrics{}
+	}
+	return a.config.Metrics
+}
+
+// observePools registers the adapter's pools with its metrics.
+func (a *MySQLAdapter) observePools() {
+	m := a.metrics()
+	a.unobserve = append(a.unobserve, m.ObservePool("primary", a.db.Stats))
+	if a.replicas != nil {
+		for _, r := range a.replicas.replicas {
+			a.unobserve = append(a.unobserve, m.ObservePool(r.name, r.db.Stats))
+		}
+	}
+}
+
+// unobservePools unregisters the adapter's pools from its metrics.
+func (a *MySQLAdapter) unobservePools() {
+	for _, unregister := range a.unobserve {
+		unregister()
+	}
+	a.unobserve = nil
+}
+
+// operation tracks an adapter method call for tracing and metrics.
+type operation struct {
+	a       *MySQLAdapter
+	ctx     context.Context
+	span    trace.Span
+	start   time.Time
+	kind    adapter.OperationType
+	mapping string
+	cancel  context.CancelFunc
+}
+
+// begin starts tracking an adapter method, applies the mapping's timeout and
+// checks that the adapter can run it. If it cannot, the operation is ended
+// with the returned error.
+func (a *MySQLAdapter) begin(ctx context.Context, method string, kind adapter.OperationType, mapping string, attrs ...attribute.KeyValue) (context.Context, *operation, error) {
+	ctx, span := a.tracing.startOperation(ctx, method, string(kind), attrs...)
+	if a.config.SQLComments.Enabled {
+		ctx = withCommentTags(ctx, kind, mapping)
+	}
+	cancel := func() {}
+	if d := a.queryTimeout(mapping); d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+	}
+	o := &operation{a: a, ctx: ctx, span: span, start: time.Now(), kind: kind, mapping: mapping, cancel: cancel}
+	if err := a.checkReady(); err != nil {
+		o.observe(err, 0, 0)
+		return ctx, nil, err
+	}
+	return ctx, o, nil
+}
+
+// end records the outcome of an operation let through by begin.
+func (o *operation) end(err error, rowsRead, rowsWritten int64) {
+	o.a.finish(err)
+	o.observe(err, rowsRead, rowsWritten)
+}
+
+// observe reports the operation to tracing and metrics and releases its
+// timeout.
+func (o *operation) observe(err error, rowsRead, rowsWritten int64) {
+	defer o.cancel()
+
+	var attrs []attribute.KeyValue
+	if rowsRead > 0 || o.kind == adapter.OpFetch {
+		attrs = append(attrs, attrRowsReturned.Int64(rowsRead))
+	}
+	if rowsWritten > 0 {
+		attrs = append(attrs, attrRowsAffected.Int64(rowsWritten))
+	}
+	endOperation(o.span, err, attrs...)
+
+	o.a.metrics().ObserveOperation(o.ctx, OperationObservation{
+		Kind:        o.kind,
+		Mapping:     o.mapping,
+		Duration:    time.Since(o.start),
+		RowsRead:    rowsRead,
+		RowsWritten: rowsWritten,
+		ErrorClass:  ErrorClass(err),
+	})
+}
+
+// fromTable matches the first table of a query.
+var fromTable = regexp.MustCompile("(?i)\\bFROM\\s+([`\\w.]+)")
+
+// fetchMapping returns the table a fetch query reads from.
+func fetchMapping(query string) string {
+	if m := fromTable.FindStringSubmatch(query); m != nil {
+		return strings.ReplaceAll(m[1], "`", "")
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// recordingMetrics remembers observations and registered pools.
+type recordingMetrics struct {
+	mu           sync.Mutex
+	observations []OperationObservation
+	pools        map[string]func() sql.DBStats
+}
+
+func (m *recordingMetrics) ObserveOperation(ctx context.Context, o OperationObservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations = append(m.observations, o)
+}
+
+func (m *recordingMetrics) ObservePool(pool string, stats func() sql.DBStats) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pools == nil {
+		m.pools = make(map[string]func() sql.DBStats)
+	}
+	m.pools[pool] = stats
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.pools, pool)
+	}
+}
+
+func TestMySQLAdapter_Metrics(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"SELECT id FROM `users` WHERE active = ?": {columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}, {int64(2)}}},
+		"DELETE FROM users WHERE id = ?":          {},
+	}}
+	m := &recordingMetrics{}
+	a, err := NewMySQLAdapterWithOptions(WithMetrics(m))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Operations rejected before connecting are observed too
+	if err := a.Delete(context.Background(), &adapter.Operation{Statement: "users"}, []interface{}{1}); err == nil {
+		t.Fatal("expected error before Connect")
+	}
+
+	a.db = sql.OpenDB(script)
+	a.replicas = &replicaSet{replicas: []*replica{{name: "replica-1", db: sql.OpenDB(script)}}}
+	a.observePools()
+
+	action := &adapter.Action{Name: "active_ids", Statement: "SELECT id FROM `users` WHERE active = {active}", Result: &adapter.ResultMapping{}}
+	if _, err := a.Execute(context.Background(), action, map[string]interface{}{"active": true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	op := &adapter.Operation{Statement: "users", Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}}}
+	if err := a.Delete(context.Background(), op, []interface{}{1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(m.observations) != 3 {
+		t.Fatalf("expected 3 observations, got %+v", m.observations)
+	}
+	if o := m.observations[0]; o.Kind != adapter.OpDelete || o.ErrorClass != ErrorClassOther {
+		t.Errorf("unexpected rejected observation %+v", o)
+	}
+	if o := m.observations[1]; o.Kind != adapter.OpAction || o.Mapping != "active_ids" || o.RowsRead != 2 || o.ErrorClass != "" || o.Duration <= 0 {
+		t.Errorf("unexpected action observation %+v", o)
+	}
+	if o := m.observations[2]; o.Mapping != "users" || o.RowsWritten != 1 {
+		t.Errorf("unexpected delete observation %+v", o)
+	}
+
+	if len(m.pools) != 2 || m.pools["primary"] == nil || m.pools["replica-1"] == nil {
+		t.Errorf("expected primary and replica pools, got %v", m.pools)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.pools) != 0 {
+		t.Error("expected pools to be unregistered on Close")
+	}
+}
+
+func TestFetchMapping(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE id = ?":          "users",
+		"select u.id from `app`.`users` u":          "app.users",
+		"SELECT id FROM orders JOIN users ON x = y": "orders",
+		"SELECT 1": "",
+	}
+	for query, want := range tests {
+		if got := fetchMapping(query); got != want {
+			t.Errorf("%s: expected %q, got %q", query, want, got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package otelmetrics reports MySQL adapter measurements as OpenTelemetry
+// metrics. It is kept out of the adapter package so that only applications
+// using it depend on the OpenTelemetry metrics API.
+package otelmetrics
+
+import (
+	"context"
+	"database/sql"
+
+	mysql "github.com/toutaio/toutago-datamapper-mysql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// scope is the instrumentation scope of the metrics.
+const scope = "github.com/toutaio/toutago-datamapper-mysql"
+
+// mappingKey labels measurements with the table or action name.
+const mappingKey = attribute.Key("mysql.mapping")
+
+// Metrics implements mysql.Metrics with OpenTelemetry instruments.
+type Metrics struct {
+	meter metric.Meter
+
+	duration    metric.Float64Histogram
+	errors      metric.Int64Counter
+	rowsRead    metric.Int64Counter
+	rowsWritten metric.Int64Counter
+
+	usage        metric.Int64ObservableUpDownCounter
+	max          metric.Int64ObservableUpDownCounter
+	waitCount    metric.Int64ObservableCounter
+	waitDuration metric.Float64ObservableCounter
+}
+
+var _ mysql.Metrics = (*Metrics)(nil)
+
+// New creates the instruments on a meter of mp.
+func New(mp metric.MeterProvider) (*Metrics, error) {
+	m := &Metrics{meter: mp.Meter(scope)}
+	var err error
+	if m.duration, err = m.meter.Float64Histogram("db.client.operation.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of adapter operations")); err != nil {
+		return nil, err
+	}
+	if m.errors, err = m.meter.Int64Counter("mysql.client.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Failed adapter operations by error class")); err != nil {
+		return nil, err
+	}
+	if m.rowsRead, err = m.meter.Int64Counter("mysql.client.rows_read",
+		metric.WithUnit("{row}"),
+		metric.WithDescription("Rows returned by adapter operations")); err != nil {
+		return nil, err
+	}
+	if m.rowsWritten, err = m.meter.Int64Counter("mysql.client.rows_written",
+		metric.WithUnit("{row}"),
+		metric.WithDescription("Rows inserted, changed or deleted by adapter operations")); err != nil {
+		return nil, err
+	}
+	if m.usage, err = m.meter.Int64ObservableUpDownCounter(semconv.DBClientConnectionsUsageName,
+		metric.WithUnit(semconv.DBClientConnectionsUsageUnit),
+		metric.WithDescription(semconv.DBClientConnectionsUsageDescription)); err != nil {
+		return nil, err
+	}
+	if m.max, err = m.meter.Int64ObservableUpDownCounter(semconv.DBClientConnectionsMaxName,
+		metric.WithUnit(semconv.DBClientConnectionsMaxUnit),
+		metric.WithDescription(semconv.DBClientConnectionsMaxDescription)); err != nil {
+		return nil, err
+	}
+	if m.waitCount, err = m.meter.Int64ObservableCounter("mysql.client.pool.wait_count",
+		metric.WithUnit("{wait}"),
+		metric.WithDescription("Times a connection had to be waited for")); err != nil {
+		return nil, err
+	}
+	if m.waitDuration, err = m.meter.Float64ObservableCounter("mysql.client.pool.wait_duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Total time spent waiting for a connection")); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ObserveOperation implements mysql.Metrics.
+func (m *Metrics) ObserveOperation(ctx context.Context, o mysql.OperationObservation) {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMySQL,
+		semconv.DBOperation(string(o.Kind)),
+		mappingKey.String(o.Mapping),
+	}
+	if o.ErrorClass != "" {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(o.ErrorClass))
+	}
+	set := metric.WithAttributes(attrs...)
+
+	m.duration.Record(ctx, o.Duration.Seconds(), set)
+	if o.ErrorClass != "" {
+		m.errors.Add(ctx, 1, set)
+	}
+	if o.RowsRead > 0 {
+		m.rowsRead.Add(ctx, o.RowsRead, set)
+	}
+	if o.RowsWritten > 0 {
+		m.rowsWritten.Add(ctx, o.RowsWritten, set)
+	}
+}
+
+// ObservePool implements mysql.Metrics, reporting the pool's statistics
+// whenever the metrics are collected.
+func (m *Metrics) ObservePool(pool string, stats func() sql.DBStats) func() {
+	name := semconv.PoolName(pool)
+	idle := metric.WithAttributes(name, semconv.StateIdle)
+	used := metric.WithAttributes(name, semconv.StateUsed)
+	labels := metric.WithAttributes(name)
+
+	reg, err := m.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		s := stats()
+		o.ObserveInt64(m.usage, int64(s.Idle), idle)
+		o.ObserveInt64(m.usage, int64(s.InUse), used)
+		o.ObserveInt64(m.max, int64(s.MaxOpenConnections), labels)
+		o.ObserveInt64(m.waitCount, s.WaitCount, labels)
+		o.ObserveFloat64(m.waitDuration, s.WaitDuration.Seconds(), labels)
+		return nil
+	}, m.usage, m.max, m.waitCount, m.waitDuration)
+	if err != nil {
+		otel.Handle(err)
+		return func() {}
+	}
+	return func() { _ = reg.Unregister() }
+}
@@ -0,0 +1,107 @@
+package otelmetrics
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mysql "github.com/toutaio/toutago-datamapper-mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collect returns the collected metrics by name.
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metrics := make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+func TestMetrics_ObserveOperation(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	m, err := New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	m.ObserveOperation(ctx, mysql.OperationObservation{Kind: adapter.OpFetch, Mapping: "users", Duration: 20 * time.Millisecond, RowsRead: 3})
+	m.ObserveOperation(ctx, mysql.OperationObservation{Kind: adapter.OpInsert, Mapping: "users", Duration: time.Millisecond, ErrorClass: mysql.ErrorClassConstraint})
+	m.ObserveOperation(ctx, mysql.OperationObservation{Kind: adapter.OpUpdate, Mapping: "users", Duration: time.Millisecond, RowsWritten: 2})
+
+	got := collect(t, reader)
+	duration := got["db.client.operation.duration"].Data.(metricdata.Histogram[float64])
+	if len(duration.DataPoints) != 3 {
+		t.Fatalf("expected a histogram series per operation, got %d", len(duration.DataPoints))
+	}
+	for _, dp := range duration.DataPoints {
+		if v, _ := dp.Attributes.Value("mysql.mapping"); v.AsString() != "users" {
+			t.Errorf("expected mapping label, got %v", dp.Attributes)
+		}
+	}
+
+	errs := got["mysql.client.errors"].Data.(metricdata.Sum[int64])
+	if len(errs.DataPoints) != 1 || errs.DataPoints[0].Value != 1 {
+		t.Fatalf("expected one error, got %+v", errs.DataPoints)
+	}
+	if v, _ := errs.DataPoints[0].Attributes.Value("error.type"); v.AsString() != "constraint" {
+		t.Errorf("expected constraint error class, got %v", v.AsString())
+	}
+	if read := got["mysql.client.rows_read"].Data.(metricdata.Sum[int64]); read.DataPoints[0].Value != 3 {
+		t.Errorf("expected 3 rows read, got %d", read.DataPoints[0].Value)
+	}
+	if written := got["mysql.client.rows_written"].Data.(metricdata.Sum[int64]); written.DataPoints[0].Value != 2 {
+		t.Errorf("expected 2 rows written, got %d", written.DataPoints[0].Value)
+	}
+}
+
+func TestMetrics_ObservePool(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	m, err := New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unregister := m.ObservePool("primary", func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 10, InUse: 3, Idle: 2, WaitCount: 4, WaitDuration: 2 * time.Second}
+	})
+
+	got := collect(t, reader)
+	usage := got["db.client.connections.usage"].Data.(metricdata.Sum[int64])
+	byState := map[string]int64{}
+	for _, dp := range usage.DataPoints {
+		state, _ := dp.Attributes.Value("state")
+		byState[state.AsString()] = dp.Value
+		if pool, _ := dp.Attributes.Value("pool.name"); pool.AsString() != "primary" {
+			t.Errorf("expected pool label, got %v", dp.Attributes)
+		}
+	}
+	if byState["idle"] != 2 || byState["used"] != 3 {
+		t.Errorf("unexpected usage %v", byState)
+	}
+	if max := got["db.client.connections.max"].Data.(metricdata.Sum[int64]); max.DataPoints[0].Value != 10 {
+		t.Errorf("expected max 10, got %d", max.DataPoints[0].Value)
+	}
+	if waits := got["mysql.client.pool.wait_count"].Data.(metricdata.Sum[int64]); waits.DataPoints[0].Value != 4 {
+		t.Errorf("expected 4 waits, got %d", waits.DataPoints[0].Value)
+	}
+	if wait := got["mysql.client.pool.wait_duration"].Data.(metricdata.Sum[float64]); wait.DataPoints[0].Value != 2 {
+		t.Errorf("expected 2s waited, got %g", wait.DataPoints[0].Value)
+	}
+
+	unregister()
+	if _, ok := collect(t, reader)["db.client.connections.usage"]; ok {
+		t.Error("expected no pool metrics after unregistering")
+	}
+}
@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// queryer is the subset of *sql.DB, *sql.Conn and *sql.Tx used to run
+// statements, so related statements can share a single connection.
+type queryer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertGenerated splits the server-generated fields of an insert into the
+// auto-increment key, filled from LastInsertId, and the columns populated by
+// server defaults, which can be re-selected after the insert.
+//
+// The key is the generated field that is also an identifier, if any. An
+// operation without identifiers or client-generated IDs has its first
+// generated field as the key; with client-generated IDs the row already has
+// its identity and every server-generated field is a default. Other
+// identifiers locate the row and are not defaults.
+func insertGenerated(op *adapter.Operation, generated []adapter.PropertyMapping, clientIDs bool) (*adapter.PropertyMapping, []adapter.PropertyMapping) {
+	if len(generated) == 0 {
+		return nil, nil
+	}
+
+	if len(op.Identifier) == 0 && !clientIDs {
+		key := generated[0]
+		return &key, generated[1:]
+	}
+
+	var key *adapter.PropertyMapping
+	var defaults []adapter.PropertyMapping
+	for _, gen := range generated {
+		if isIdentifierField(op, gen.DataField) {
+			if key == nil {
+				gen := gen
+				key = &gen
+			}
+			continue
+		}
+		defaults = append(defaults, gen)
+	}
+	return key, defaults
+}
+
+// refetchFields returns the generated fields re-selected after a write: none
+// unless RefetchGenerated is set, and never the identifiers locating the row.
+func (a *MySQLAdapter) refetchFields(op *adapter.Operation, generated []adapter.PropertyMapping) []adapter.PropertyMapping {
+	if !a.config.RefetchGenerated {
+		return nil
+	}
+	var fields []adapter.PropertyMapping
+	for _, gen := range generated {
+		if !isIdentifierField(op, gen.DataField) {
+			fields = append(fields, gen)
+		}
+	}
+	return fields
+}
+
+// insertedRowWhere builds the WHERE clause locating a freshly inserted row by
+// its identifiers, its auto-increment key or its client-generated IDs.
+func (a *MySQLAdapter) insertedRowWhere(op *adapter.Operation, key *adapter.PropertyMapping, clientIDs []adapter.PropertyMapping, data map[string]interface{}) ([]string, []interface{}, error) {
+	fields := op.Identifier
+	switch {
+	case len(fields) > 0:
+	case key != nil:
+		fields = []adapter.PropertyMapping{*key}
+	case len(clientIDs) > 0:
+		fields = clientIDs
+	default:
+		return nil, nil, fmt.Errorf("mysql: cannot locate inserted row in %s without a key", op.Statement)
+	}
+
+	whereClauses, values, err := identifierWhere(adapter.OpInsert, &adapter.Operation{Statement: op.Statement, Identifier: fields}, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.encodeIDs(fields, values); err != nil {
+		return nil, nil, err
+	}
+	return whereClauses, values, nil
+}
+
+// refetch re-selects server-populated columns of the row matching the WHERE
+// clauses and writes them back into data. The statement runs through the
+// interceptor chain like the write it follows.
+func (a *MySQLAdapter) refetch(ctx context.Context, q queryer, op *adapter.Operation, fields []adapter.PropertyMapping, whereClauses []string, values []interface{}, data map[string]interface{}) error {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.DataField
+	}
+
+	call := &Call{Kind: adapter.OpFetch, Operation: op, SQL: refetchQuery(op.Statement, columns, whereClauses), Args: values}
+	result, err := a.queryCall(ctx, q, call, fields)
+	if err != nil {
+		return fmt.Errorf("mysql: failed to re-fetch generated columns: %w", err)
+	}
+	if len(result.Rows) == 0 {
+		return adapter.ErrNotFound
+	}
+
+	row, _ := result.Rows[0].(map[string]interface{})
+	for _, f := range fields {
+		data[f.ObjectField] = row[f.DataField]
+	}
+	return nil
+}
+
+// refetchQuery builds a statement selecting columns from the row matching the WHERE clauses.
+func refetchQuery(table string, columns, whereClauses []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT 1",
+		strings.Join(columns, ", "),
+		table,
+		strings.Join(whereClauses, " AND "))
+}
@@ -0,0 +1,250 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestInsertGenerated(t *testing.T) {
+	id := adapter.PropertyMapping{ObjectField: "ID", DataField: "id"}
+	createdAt := adapter.PropertyMapping{ObjectField: "CreatedAt", DataField: "created_at"}
+	version := adapter.PropertyMapping{ObjectField: "Version", DataField: "version"}
+
+	tests := []struct {
+		name      string
+		op        *adapter.Operation
+		clientIDs bool
+		key       string
+		defaults  []string
+	}{
+		{
+			name: "no generated fields",
+			op:   &adapter.Operation{},
+		},
+		{
+			name: "auto-increment only",
+			op:   &adapter.Operation{Generated: []adapter.PropertyMapping{id}},
+			key:  "id",
+		},
+		{
+			name:     "first generated field is the key",
+			op:       &adapter.Operation{Generated: []adapter.PropertyMapping{id, createdAt, version}},
+			key:      "id",
+			defaults: []string{"created_at", "version"},
+		},
+		{
+			name: "key taken from identifiers",
+			op: &adapter.Operation{
+				Identifier: []adapter.PropertyMapping{id},
+				Generated:  []adapter.PropertyMapping{createdAt, id},
+			},
+			key:      "id",
+			defaults: []string{"created_at"},
+		},
+		{
+			name: "identifiers are not defaults",
+			op: &adapter.Operation{
+				Identifier: []adapter.PropertyMapping{id, version},
+				Generated:  []adapter.PropertyMapping{id, version, createdAt},
+			},
+			key:      "id",
+			defaults: []string{"created_at"},
+		},
+		{
+			name:      "client-generated identity",
+			op:        &adapter.Operation{Generated: []adapter.PropertyMapping{createdAt, version}},
+			clientIDs: true,
+			defaults:  []string{"created_at", "version"},
+		},
+		{
+			name: "client-provided key",
+			op: &adapter.Operation{
+				Identifier: []adapter.PropertyMapping{id},
+				Generated:  []adapter.PropertyMapping{createdAt},
+			},
+			defaults: []string{"created_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, defaults := insertGenerated(tt.op, tt.op.Generated, tt.clientIDs)
+
+			gotKey := ""
+			if key != nil {
+				gotKey = key.DataField
+			}
+			if gotKey != tt.key {
+				t.Errorf("expected key '%s', got '%s'", tt.key, gotKey)
+			}
+
+			if len(defaults) != len(tt.defaults) {
+				t.Fatalf("expected %d defaults, got %d", len(tt.defaults), len(defaults))
+			}
+			for i, d := range defaults {
+				if d.DataField != tt.defaults[i] {
+					t.Errorf("expected default '%s', got '%s'", tt.defaults[i], d.DataField)
+				}
+			}
+		})
+	}
+}
+
+func TestInsertedRowWhere(t *testing.T) {
+	a := NewMySQLAdapter()
+	key := &adapter.PropertyMapping{ObjectField: "ID", DataField: "id"}
+	data := map[string]interface{}{"ID": int64(12)}
+
+	clauses, values, err := a.insertedRowWhere(&adapter.Operation{Statement: "users"}, key, nil, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clauses) != 1 || clauses[0] != "id = ?" || values[0] != int64(12) {
+		t.Errorf("unexpected where %v %v", clauses, values)
+	}
+
+	if _, _, err := a.insertedRowWhere(&adapter.Operation{Statement: "users"}, nil, nil, data); err == nil {
+		t.Error("expected error without key or identifiers")
+	}
+}
+
+func TestMySQLAdapter_InsertRefetchesDefaults(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"INSERT INTO users (name) VALUES (?)": {exec: fakeExec{lastID: 12, affected: 1}},
+		"SELECT created_at, version FROM users WHERE id = ? LIMIT 1": {
+			columns: []string{"created_at", "version"},
+			rows:    [][]driver.Value{{"2026-01-02 03:04:05", int64(1)}},
+		},
+	}}
+	a := newScriptAdapter(t, script, WithRefetchGenerated())
+
+	op := &adapter.Operation{
+		Statement:  "users",
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+		Generated: []adapter.PropertyMapping{
+			{ObjectField: "ID", DataField: "id"},
+			{ObjectField: "CreatedAt", DataField: "created_at"},
+			{ObjectField: "Version", DataField: "version"},
+		},
+	}
+	obj := map[string]interface{}{"Name": "Ann"}
+	if err := a.Insert(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj["ID"] != int64(12) || obj["CreatedAt"] != "2026-01-02 03:04:05" || obj["Version"] != int64(1) {
+		t.Errorf("expected key and defaults written back, got %v", obj)
+	}
+}
+
+func TestMySQLAdapter_InsertRefetchesByClientID(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"INSERT INTO orders (total, id) VALUES (?, ?)": {exec: fakeExec{affected: 1}},
+		"SELECT created_at FROM orders WHERE id = ? LIMIT 1": {
+			columns: []string{"created_at"},
+			rows:    [][]driver.Value{{"2026-01-02 03:04:05"}},
+		},
+	}}
+	a := newScriptAdapter(t, script, WithRefetchGenerated())
+
+	// The client-generated ID locates the row; created_at is not the key
+	op := &adapter.Operation{
+		Statement:  "orders",
+		Properties: []adapter.PropertyMapping{{ObjectField: "Total", DataField: "total"}},
+		Generated: []adapter.PropertyMapping{
+			{ObjectField: "CreatedAt", DataField: "created_at"},
+			{ObjectField: "ID", DataField: "id", Type: "uuidv7_bin"},
+		},
+	}
+	obj := map[string]interface{}{"Total": 10}
+	if err := a.Insert(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id, _ := obj["ID"].(string); len(id) != 36 {
+		t.Errorf("expected generated UUID, got %v", obj["ID"])
+	}
+	if obj["CreatedAt"] != "2026-01-02 03:04:05" {
+		t.Errorf("expected created_at to be re-fetched, got %v", obj["CreatedAt"])
+	}
+}
+
+func TestMySQLAdapter_RefetchOptIn(t *testing.T) {
+	insert := "INSERT INTO users (name) VALUES (?)"
+	update := "UPDATE users SET name = ? WHERE id = ?"
+	script := &scriptConnector{results: map[string]fakeResult{
+		insert: {exec: fakeExec{lastID: 12, affected: 1}},
+		update: {exec: fakeExec{affected: 1}},
+		"SELECT updated_at FROM users WHERE id = ? LIMIT 1": {
+			columns: []string{"updated_at"},
+			rows:    [][]driver.Value{{"2026-01-02 03:04:05"}},
+		},
+	}}
+	op := &adapter.Operation{
+		Statement:  "users",
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+		Generated: []adapter.PropertyMapping{
+			{ObjectField: "ID", DataField: "id"},
+			{ObjectField: "UpdatedAt", DataField: "updated_at"},
+		},
+	}
+
+	// Without the option only the auto-increment key is written back
+	a := newScriptAdapter(t, script)
+	obj := map[string]interface{}{"Name": "Ann"}
+	if err := a.Insert(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.Update(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj["ID"] != int64(12) || obj["UpdatedAt"] != nil {
+		t.Errorf("expected only the key to be written back, got %v", obj)
+	}
+	if got := script.executed(); len(got) != 2 {
+		t.Errorf("expected no re-fetch, got %v", got)
+	}
+
+	// The identifier locates the row and is not re-selected
+	script.queries = nil
+	a = newScriptAdapter(t, script, WithRefetchGenerated())
+	if err := a.Update(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj["UpdatedAt"] != "2026-01-02 03:04:05" {
+		t.Errorf("expected updated_at to be re-fetched, got %v", obj["UpdatedAt"])
+	}
+	if got := script.executed(); len(got) != 2 {
+		t.Errorf("expected the update and one re-fetch, got %v", got)
+	}
+
+	// Nothing is left to re-select when the identifier is the only generated field
+	script.queries = nil
+	op.Generated = op.Generated[:1]
+	if err := a.Update(context.Background(), op, []interface{}{obj}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := script.executed(); len(got) != 1 {
+		t.Errorf("expected only the update, got %v", got)
+	}
+}
+
+func TestConfig_RefetchGenerated(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.apply(map[string]interface{}{"refetch_generated": "true"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.RefetchGenerated {
+		t.Error("expected re-fetching to be enabled")
+	}
+}
+
+func TestRefetchQuery(t *testing.T) {
+	got := refetchQuery("users", []string{"created_at", "version"}, []string{"id = ?"})
+	expected := "SELECT created_at, version FROM users WHERE id = ? LIMIT 1"
+	if got != expected {
+		t.Errorf("expected query '%s', got '%s'", expected, got)
+	}
+}
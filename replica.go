@@ -0,0 +1,370 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// Replica balancing strategies.
+const (
+	// BalanceRoundRobin sends reads to each replica in turn.
+	BalanceRoundRobin = "round_robin"
+
+	// BalanceLeastConnections sends reads to the replica with the fewest
+	// connections in use.
+	BalanceLeastConnections = "least_connections"
+)
+
+// ReplicaConfig is the endpoint of a read replica. Credentials, database,
+// TLS and pool settings are inherited from the primary.
+type ReplicaConfig struct {
+	// Host and Port locate the replica. Port defaults to the primary's.
+	Host string
+	Port int
+
+	// Socket is a unix socket path used instead of Host and Port.
+	Socket string
+
+	// DSN is a complete driver DSN or mysql:// URL for the replica.
+	DSN string
+}
+
+// String returns the replica address.
+func (r ReplicaConfig) String() string {
+	switch {
+	case r.DSN != "":
+		return r.DSN
+	case r.Socket != "":
+		return r.Socket
+	case r.Port != 0:
+		return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+	}
+	return r.Host
+}
+
+// driverConfig returns the driver configuration of the replica, derived from
+// the primary configuration.
+func (r ReplicaConfig) driverConfig(primary Config, primaryDriver *gomysql.Config) (*gomysql.Config, error) {
+	if r.DSN != "" {
+		c := primary
+		c.DSN = r.DSN
+		return c.driverConfig()
+	}
+
+	cfg := primaryDriver.Clone()
+	// Let the driver derive the TLS server name from the replica address
+	cfg.TLS = nil
+	if r.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = r.Socket
+		return cfg, nil
+	}
+
+	port := r.Port
+	if port == 0 {
+		port = primary.Port
+	}
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(r.Host, strconv.Itoa(port))
+	return cfg, nil
+}
+
+// replica is an open read replica pool.
+type replica struct {
+	name      string
+	db        *sql.DB
+	connector *connector
+
+	// excluded is set while the replica lags too far behind the primary
+	// or its lag cannot be determined.
+	excluded atomic.Bool
+}
+
+// replicaSet balances reads across replica pools.
+type replicaSet struct {
+	replicas []*replica
+	balance  string
+	next     atomic.Uint64
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// pick returns the replica for the next read, or nil when there is no
+// replica or all of them are excluded.
+func (s *replicaSet) pick() *replica {
+	if s == nil || len(s.replicas) == 0 {
+		return nil
+	}
+
+	if s.balance == BalanceLeastConnections {
+		var best *replica
+		bestInUse := 0
+		for _, r := range s.replicas {
+			if r.excluded.Load() {
+				continue
+			}
+			if inUse := r.db.Stats().InUse; best == nil || inUse < bestInUse {
+				best, bestInUse = r, inUse
+			}
+		}
+		return best
+	}
+
+	n := s.next.Add(1) - 1
+	for i := range s.replicas {
+		r := s.replicas[(n+uint64(i))%uint64(len(s.replicas))]
+		if !r.excluded.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// monitorLag checks every replica's lag each interval in the background,
+// excluding replicas more than maxLag behind the primary until they catch up.
+func (s *replicaSet) monitorLag(maxLag, interval time.Duration) {
+	s.stop = make(chan struct{})
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.checkLag(maxLag, interval)
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkLag updates which replicas are excluded for lag.
+func (s *replicaSet) checkLag(maxLag, timeout time.Duration) {
+	for _, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		lag, err := replicaLag(ctx, r.db)
+		cancel()
+		r.excluded.Store(err != nil || lag > maxLag)
+	}
+}
+
+// replicaLag returns how far a replica is behind its source, from
+// Seconds_Behind_Source (Seconds_Behind_Master before MySQL 8.0.22). A
+// server that is not a replica has no lag.
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		var mysqlErr *gomysql.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1064 {
+			return 0, err
+		}
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return 0, err
+	}
+
+	for i, col := range columns {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errors.New("mysql: replication is not running")
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("mysql: invalid replication lag %q", values[i])
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("mysql: replication lag not reported")
+}
+
+// ping verifies every replica is reachable.
+func (s *replicaSet) ping(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	for _, r := range s.replicas {
+		if err := r.db.PingContext(ctx); err != nil {
+			return fmt.Errorf("mysql: failed to ping replica %s: %w", r.name, err)
+		}
+	}
+	return nil
+}
+
+// close closes every replica pool.
+func (s *replicaSet) close() {
+	if s == nil {
+		return
+	}
+	if s.stop != nil {
+		close(s.stop)
+		s.done.Wait()
+		s.stop = nil
+	}
+	for _, r := range s.replicas {
+		_ = r.db.Close()
+	}
+}
+
+// openReplicas opens a pool for each configured replica.
+func (a *MySQLAdapter) openReplicas(cfg Config, primaryDriver *gomysql.Config) (*replicaSet, error) {
+	if len(cfg.Replicas) == 0 {
+		return nil, nil
+	}
+
+	set := &replicaSet{balance: cfg.ReplicaBalance}
+	for _, rc := range cfg.Replicas {
+		driverCfg, err := rc.driverConfig(cfg, primaryDriver)
+		if err == nil {
+			var db *sql.DB
+			var conn *connector
+			db, conn, err = a.openPool(cfg, driverCfg)
+			if err == nil {
+				set.replicas = append(set.replicas, &replica{name: rc.String(), db: db, connector: conn})
+				continue
+			}
+		}
+		set.close()
+		return nil, fmt.Errorf("mysql: replica %s: %w", rc, err)
+	}
+	return set, nil
+}
+
+// startLagMonitor starts excluding lagging replicas when a maximum lag is
+// configured.
+func (a *MySQLAdapter) startLagMonitor(cfg Config) {
+	if a.replicas == nil || cfg.MaxReplicaLag <= 0 {
+		return
+	}
+	interval := cfg.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	a.replicas.monitorLag(cfg.MaxReplicaLag, interval)
+}
+
+// primaryKey is the context key forcing reads to the primary.
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads go to the primary instead of a
+// replica, for example right after a write that must be visible.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// usePrimary reports whether ctx forces reads to the primary.
+func usePrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// reader returns where reads for ctx run, the pool it belongs to and a
+// function releasing it. After a write through a read-your-writes context, a
+// replica is only used once it has applied the write; otherwise the read
+// falls back to the primary.
+func (a *MySQLAdapter) reader(ctx context.Context) (queryer, *sql.DB, func()) {
+	release := func() {}
+	if usePrimary(ctx) {
+		return a.db, a.db, release
+	}
+	r := a.replicas.pick()
+	if r == nil {
+		return a.db, a.db, release
+	}
+
+	t := tokenFrom(ctx)
+	if t == nil {
+		return r.db, r.db, release
+	}
+	wrote, gtid := t.state()
+	if !wrote {
+		return r.db, r.db, release
+	}
+	if gtid == "" || a.config.GTIDWaitTimeout <= 0 {
+		return a.db, a.db, release
+	}
+
+	// Wait on the connection that will serve the read
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return a.db, a.db, release
+	}
+	if !a.waitForGTID(ctx, conn, gtid) {
+		_ = conn.Close()
+		return a.db, a.db, release
+	}
+	return conn, r.db, func() { _ = conn.Close() }
+}
+
+// isReadOnlyAction reports whether an action may run on a replica: it is
+// listed in ReadOnlyActions or is a plain SELECT without locking reads.
+func (a *MySQLAdapter) isReadOnlyAction(action *adapter.Action) bool {
+	for _, name := range a.config.ReadOnlyActions {
+		if name == action.Name {
+			return true
+		}
+	}
+	return isReadOnlyStatement(action.Statement)
+}
+
+// isReadOnlyStatement reports whether a statement only reads data.
+// Locking reads and SELECT ... INTO are treated as writes.
+func isReadOnlyStatement(statement string) bool {
+	fields := strings.Fields(strings.ToUpper(statement))
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "SHOW", "DESCRIBE", "DESC", "EXPLAIN":
+		return true
+	case "SELECT", "(SELECT":
+	default:
+		return false
+	}
+
+	for i, f := range fields {
+		switch f {
+		case "INTO", "LOCK":
+			return false
+		case "FOR":
+			if i+1 < len(fields) && (fields[i+1] == "UPDATE" || fields[i+1] == "SHARE") {
+				return false
+			}
+		}
+	}
+	return true
+}
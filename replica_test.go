@@ -0,0 +1,207 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// fakeReplica returns a replica backed by a fake connector.
+func fakeReplica(name string) *replica {
+	return &replica{name: name, db: sql.OpenDB(&fakeConnector{})}
+}
+
+func TestConfig_Replicas(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		ConfigReplicas: []interface{}{
+			"replica-1.internal",
+			"replica-2.internal:3307",
+			"mysql://reader:pw@replica-3.internal/app",
+			map[string]interface{}{"socket": "/var/run/mysqld/replica.sock"},
+		},
+		ConfigReplicaBalance:  BalanceLeastConnections,
+		ConfigReadOnlyActions: []interface{}{"monthly_report"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ReplicaConfig{
+		{Host: "replica-1.internal"},
+		{Host: "replica-2.internal", Port: 3307},
+		{DSN: "mysql://reader:pw@replica-3.internal/app"},
+		{Socket: "/var/run/mysqld/replica.sock"},
+	}
+	if len(cfg.Replicas) != len(want) {
+		t.Fatalf("expected %d replicas, got %d", len(want), len(cfg.Replicas))
+	}
+	for i := range want {
+		if cfg.Replicas[i] != want[i] {
+			t.Errorf("replica %d: expected %+v, got %+v", i, want[i], cfg.Replicas[i])
+		}
+	}
+	if cfg.ReplicaBalance != BalanceLeastConnections || len(cfg.ReadOnlyActions) != 1 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	cfg.ReplicaBalance = "random"
+	cfg.Replicas = append(cfg.Replicas, ReplicaConfig{})
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected invalid balance and empty replica to be rejected")
+	}
+
+	if err := cfg.apply(map[string]interface{}{ConfigReplicas: []interface{}{map[string]interface{}{"hots": "x"}}}); err == nil {
+		t.Error("expected unknown replica key to be rejected")
+	}
+}
+
+func TestReplicaConfig_DriverConfig(t *testing.T) {
+	primary := DefaultConfig()
+	primary.Host = "primary.internal"
+	primary.Port = 3307
+	primary.User = "app"
+	primary.Password = "secret"
+	primary.Database = "app"
+	primary.SSL = "true"
+	primaryDriver, err := primary.driverConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := ReplicaConfig{Host: "replica.internal"}.driverConfig(primary, primaryDriver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Addr != "replica.internal:3307" || cfg.User != "app" || cfg.Passwd != "secret" || cfg.DBName != "app" {
+		t.Errorf("expected replica to inherit primary settings, got %+v", cfg)
+	}
+	if cfg.TLS != nil || cfg.TLSConfig != "true" {
+		t.Error("expected tls to be re-derived for the replica host")
+	}
+	if primaryDriver.Addr != "primary.internal:3307" {
+		t.Error("expected primary driver config to be left untouched")
+	}
+
+	cfg, err = ReplicaConfig{DSN: "mysql://reader:pw@replica.internal:3306/app"}.driverConfig(primary, primaryDriver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.User != "reader" || !cfg.ParseTime {
+		t.Errorf("expected replica dsn to be used with enforced params, got %+v", cfg)
+	}
+
+	cfg, _ = ReplicaConfig{Socket: "/tmp/replica.sock"}.driverConfig(primary, primaryDriver)
+	if cfg.Net != "unix" || cfg.Addr != "/tmp/replica.sock" {
+		t.Errorf("expected unix socket, got %s %s", cfg.Net, cfg.Addr)
+	}
+}
+
+func TestReplicaSet_RoundRobin(t *testing.T) {
+	set := &replicaSet{replicas: []*replica{fakeReplica("a"), fakeReplica("b"), fakeReplica("c")}}
+	defer set.close()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, set.pick().name)
+	}
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" || got[3] != "a" {
+		t.Errorf("expected round robin order, got %v", got)
+	}
+
+	set.replicas[1].excluded.Store(true)
+	for i := 0; i < 4; i++ {
+		if set.pick().name == "b" {
+			t.Fatal("expected excluded replica to be skipped")
+		}
+	}
+}
+
+func TestReplicaSet_LeastConnections(t *testing.T) {
+	busy, idle := fakeReplica("busy"), fakeReplica("idle")
+	set := &replicaSet{replicas: []*replica{busy, idle}, balance: BalanceLeastConnections}
+	defer set.close()
+
+	conn, err := busy.db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	for i := 0; i < 3; i++ {
+		if r := set.pick(); r != idle {
+			t.Errorf("expected replica with fewest connections, got %s", r.name)
+		}
+	}
+
+	idle.excluded.Store(true)
+	if r := set.pick(); r != busy {
+		t.Error("expected excluded replica to be skipped")
+	}
+}
+
+func TestMySQLAdapter_Reader(t *testing.T) {
+	a := NewMySQLAdapter()
+	a.db = sql.OpenDB(&fakeConnector{})
+	defer func() { _ = a.db.Close() }()
+
+	if q, _, _ := a.reader(context.Background()); q != a.db {
+		t.Error("expected primary without replicas")
+	}
+
+	r := fakeReplica("r")
+	a.replicas = &replicaSet{replicas: []*replica{r}}
+	defer a.replicas.close()
+
+	if q, _, _ := a.reader(context.Background()); q != r.db {
+		t.Error("expected reads to go to the replica")
+	}
+	if q, _, _ := a.reader(WithPrimary(context.Background())); q != a.db {
+		t.Error("expected WithPrimary to force the primary")
+	}
+
+	r.excluded.Store(true)
+	if q, _, _ := a.reader(context.Background()); q != a.db {
+		t.Error("expected primary when every replica is excluded")
+	}
+}
+
+func TestMySQLAdapter_IsReadOnlyAction(t *testing.T) {
+	a := NewMySQLAdapter()
+	a.config.ReadOnlyActions = []string{"report"}
+
+	tests := []struct {
+		action *adapter.Action
+		want   bool
+	}{
+		{&adapter.Action{Statement: "SELECT * FROM users"}, true},
+		{&adapter.Action{Statement: "  select count(*) from users"}, true},
+		{&adapter.Action{Statement: "SHOW TABLES"}, true},
+		{&adapter.Action{Statement: "SELECT * FROM users FOR UPDATE"}, false},
+		{&adapter.Action{Statement: "SELECT * FROM users FOR SHARE"}, false},
+		{&adapter.Action{Statement: "SELECT * FROM users LOCK IN SHARE MODE"}, false},
+		{&adapter.Action{Statement: "SELECT 1 INTO @x"}, false},
+		{&adapter.Action{Statement: "CALL cleanup()"}, false},
+		{&adapter.Action{Name: "report", Statement: "CALL monthly_report()"}, true},
+		{&adapter.Action{Statement: "UPDATE users SET active = 0"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := a.isReadOnlyAction(tt.action); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.action.Statement, tt.want, got)
+		}
+	}
+}
+
+func TestReplicaSet_Nil(t *testing.T) {
+	var set *replicaSet
+	if set.pick() != nil {
+		t.Error("expected no replica from nil set")
+	}
+	if err := set.ping(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	set.close()
+}
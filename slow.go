@@ -0,0 +1,189 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// explainTimeout bounds the EXPLAIN of a slow query.
+const explainTimeout = 10 * time.Second
+
+// SlowQueryConfig configures slow query detection for Fetch and Execute.
+type SlowQueryConfig struct {
+	// Threshold is the duration above which a statement is reported as
+	// slow. Zero disables slow query detection.
+	Threshold time.Duration
+
+	// ExplainRate is the fraction (0-1] of slow statements whose plan is
+	// captured with EXPLAIN FORMAT=JSON. Zero captures no plans.
+	ExplainRate float64
+
+	// OnSlowQuery receives every slow query from a background goroutine,
+	// after its plan has been captured.
+	OnSlowQuery func(SlowQuery)
+}
+
+// enabled reports whether slow queries are detected.
+func (c SlowQueryConfig) enabled() bool {
+	return c.Threshold > 0
+}
+
+// SlowQuery describes a statement that exceeded the slow query threshold.
+type SlowQuery struct {
+	// Kind is the operation kind: fetch or action.
+	Kind adapter.OperationType
+
+	// Mapping is the table a fetch reads from, or the name of an action.
+	Mapping string
+
+	// SQL is the statement that ran.
+	SQL string
+
+	// Fingerprint is the statement with literals replaced by ?, lists of
+	// placeholders collapsed to ?+ and whitespace normalized, so that
+	// statements differing only in values share it.
+	Fingerprint string
+
+	// Duration is how long the statement took.
+	Duration time.Duration
+
+	// ArgTypes holds the Go type of each argument, without its value.
+	ArgTypes []string
+
+	// Explain is the EXPLAIN FORMAT=JSON output of the statement, if it
+	// was sampled for a plan.
+	Explain json.RawMessage
+
+	// ExplainErr is the error of the EXPLAIN, if it failed.
+	ExplainErr error
+}
+
+// slowQueries reports slow statements in the background.
+type slowQueries struct {
+	wg         sync.WaitGroup
+	explaining atomic.Bool
+}
+
+// wait blocks until pending reports have been delivered.
+func (s *slowQueries) wait() {
+	s.wg.Wait()
+}
+
+// watchSlow wraps run so fetches and actions exceeding the slow query
+// threshold are reported.
+func (a *MySQLAdapter) watchSlow(run Handler) Handler {
+	cfg := a.config.SlowQuery
+	if !cfg.enabled() {
+		return run
+	}
+	return func(ctx context.Context, call *Call) (*Result, error) {
+		start := time.Now()
+		res, err := run(ctx, call)
+		if d := time.Since(start); d > cfg.Threshold && (call.Kind == adapter.OpFetch || call.Kind == adapter.OpAction) {
+			a.reportSlow(ctx, cfg, call, d)
+		}
+		return res, err
+	}
+}
+
+// reportSlow counts a slow statement and hands it to the hook off the
+// calling goroutine, capturing its plan first if sampled on the pool that
+// served it. Only one plan is captured at a time; slow statements meanwhile
+// are reported without one.
+func (a *MySQLAdapter) reportSlow(ctx context.Context, cfg SlowQueryConfig, call *Call, d time.Duration) {
+	a.stats.slowQueries.Add(1)
+	if cfg.OnSlowQuery == nil {
+		return
+	}
+
+	q := SlowQuery{
+		Kind:        call.Kind,
+		SQL:         call.SQL,
+		Fingerprint: fingerprint(call.SQL),
+		Duration:    d,
+		ArgTypes:    make([]string, len(call.Args)),
+	}
+	if call.Action != nil {
+		q.Mapping = call.Action.Name
+	} else {
+		q.Mapping = fetchMapping(call.SQL)
+	}
+	for i, arg := range call.Args {
+		q.ArgTypes[i] = fmt.Sprintf("%T", arg)
+	}
+	args := append([]interface{}(nil), call.Args...)
+	pool := a.servingPool(ctx)
+
+	explain := cfg.ExplainRate > 0 && explainable(call.SQL) && rand.Float64() < cfg.ExplainRate &&
+		a.slow.explaining.CompareAndSwap(false, true)
+
+	a.slow.wg.Add(1)
+	go func() {
+		defer a.slow.wg.Done()
+		if explain {
+			q.Explain, q.ExplainErr = explainPlan(pool, q.SQL, args)
+			a.slow.explaining.Store(false)
+		}
+		cfg.OnSlowQuery(q)
+	}()
+}
+
+// servingPoolKey carries the pool serving the statements of a read.
+type servingPoolKey struct{}
+
+// withServingPool records db as the pool serving the statements of ctx, so
+// slow ones are explained where they ran.
+func withServingPool(ctx context.Context, db *sql.DB) context.Context {
+	return context.WithValue(ctx, servingPoolKey{}, db)
+}
+
+// servingPool returns the pool serving the statements of ctx, the primary
+// unless a read recorded a replica.
+func (a *MySQLAdapter) servingPool(ctx context.Context) *sql.DB {
+	if db, ok := ctx.Value(servingPoolKey{}).(*sql.DB); ok && db != nil {
+		return db
+	}
+	return a.db
+}
+
+// explainPlan returns the EXPLAIN FORMAT=JSON plan of a statement on db.
+func explainPlan(db *sql.DB, query string, args []interface{}) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+
+	var plan string
+	if err := db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query, args...).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("mysql: explain failed: %w", err)
+	}
+	return json.RawMessage(plan), nil
+}
+
+// explainable reports whether MySQL can EXPLAIN a statement.
+func explainable(query string) bool {
+	switch statementVerb(query) {
+	case "SELECT", "WITH", "TABLE", "INSERT", "REPLACE", "UPDATE", "DELETE":
+		return true
+	}
+	return false
+}
+
+// placeholderList matches a comma-separated list of placeholders.
+var placeholderList = regexp.MustCompile(`\?(\s*,\s*\?)+`)
+
+// fingerprint normalizes a statement so that statements differing only in
+// values are equal.
+func fingerprint(query string) string {
+	fp := sanitizeSQL(query)
+	fp = placeholderList.ReplaceAllString(fp, "?+")
+	return strings.Join(strings.Fields(fp), " ")
+}
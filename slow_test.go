@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestMySQLAdapter_SlowQueries(t *testing.T) {
+	query := "SELECT id FROM users WHERE status = ?"
+	script := &scriptConnector{results: map[string]fakeResult{
+		query:                            {columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}},
+		"EXPLAIN FORMAT=JSON " + query:   {columns: []string{"EXPLAIN"}, rows: [][]driver.Value{{`{"query_block": {"select_id": 1}}`}}},
+		"CALL refresh_totals()":          {},
+		"DELETE FROM users WHERE id = ?": {},
+	}}
+	reports := make(chan SlowQuery, 4)
+	a, err := NewMySQLAdapterWithOptions(WithSlowQueries(SlowQueryConfig{
+		Threshold:   time.Nanosecond,
+		ExplainRate: 1,
+		OnSlowQuery: func(q SlowQuery) { reports <- q },
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+
+	ctx := context.Background()
+	active := &adapter.Action{Name: "active_ids", Statement: "SELECT id FROM users WHERE status = {status}", Result: &adapter.ResultMapping{}}
+	if _, err := a.Execute(ctx, active, map[string]interface{}{"status": "active"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := a.Execute(ctx, &adapter.Action{Name: "refresh", Statement: "CALL refresh_totals()"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	del := &adapter.Operation{Statement: "users", Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}}}
+	if err := a.Delete(ctx, del, []interface{}{1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Close waits for pending reports
+	if err := a.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(reports)
+	byName := make(map[string]SlowQuery)
+	for q := range reports {
+		byName[q.Mapping] = q
+	}
+	if len(byName) != 2 {
+		t.Fatalf("expected both actions to be reported, got %+v", byName)
+	}
+
+	q := byName["active_ids"]
+	if q.Kind != adapter.OpAction || q.SQL != query || q.Fingerprint != query || q.Duration <= 0 {
+		t.Errorf("unexpected query report %+v", q)
+	}
+	if len(q.ArgTypes) != 1 || q.ArgTypes[0] != "string" {
+		t.Errorf("unexpected arg types %v", q.ArgTypes)
+	}
+	if q.ExplainErr != nil || string(q.Explain) != `{"query_block": {"select_id": 1}}` {
+		t.Errorf("unexpected plan %s (%v)", q.Explain, q.ExplainErr)
+	}
+
+	// CALL cannot be explained
+	if q := byName["refresh"]; q.SQL != "CALL refresh_totals()" || q.Explain != nil || q.ExplainErr != nil {
+		t.Errorf("unexpected action report %+v", q)
+	}
+	if n := a.Stats().SlowQueries; n != 2 {
+		t.Errorf("expected 2 slow queries, got %d", n)
+	}
+}
+
+func TestMySQLAdapter_SlowQueryExplainedOnReplica(t *testing.T) {
+	query := "SELECT id FROM users WHERE status = ?"
+	primary := &scriptConnector{results: map[string]fakeResult{}}
+	replicaConn := &scriptConnector{results: map[string]fakeResult{
+		query:                          {columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}},
+		"EXPLAIN FORMAT=JSON " + query: {columns: []string{"EXPLAIN"}, rows: [][]driver.Value{{`{"query_block": {"select_id": 2}}`}}},
+	}}
+	reports := make(chan SlowQuery, 1)
+	a, err := NewMySQLAdapterWithOptions(WithSlowQueries(SlowQueryConfig{
+		Threshold:   time.Nanosecond,
+		ExplainRate: 1,
+		OnSlowQuery: func(q SlowQuery) { reports <- q },
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(primary)
+	a.replicas = &replicaSet{replicas: []*replica{{name: "r", db: sql.OpenDB(replicaConn)}}}
+
+	op := &adapter.Operation{Statement: "SELECT id FROM users WHERE status = {status}", Multi: true}
+	if _, err := a.Fetch(context.Background(), op, map[string]interface{}{"status": "active"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	q := <-reports
+	if q.ExplainErr != nil || string(q.Explain) != `{"query_block": {"select_id": 2}}` {
+		t.Errorf("expected the replica's plan, got %s (%v)", q.Explain, q.ExplainErr)
+	}
+	if got := primary.executed(); len(got) != 0 {
+		t.Errorf("expected nothing to run on the primary, got %v", got)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM users WHERE id = 42", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT *\n  FROM users\n  WHERE name = 'x' AND id IN (?, ?, ?)", "SELECT * FROM users WHERE name = ? AND id IN (?+)"},
+		{"INSERT INTO t (a, b) VALUES (?,?), (?,?)", "INSERT INTO t (a, b) VALUES (?+), (?+)"},
+	}
+	for _, tt := range tests {
+		if got := fingerprint(tt.query); got != tt.want {
+			t.Errorf("fingerprint(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
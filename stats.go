@@ -0,0 +1,171 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// Error classes reported by ErrorClass and counted in Stats.
+const (
+	ErrorClassConnection = "connection"
+	ErrorClassTimeout    = "timeout"
+	ErrorClassCanceled   = "canceled"
+	ErrorClassDeadlock   = "deadlock"
+	ErrorClassConstraint = "constraint"
+	ErrorClassConflict   = "conflict"
+	ErrorClassNotFound   = "not_found"
+	ErrorClassValidation = "validation"
+	ErrorClassSyntax     = "syntax"
+	ErrorClassOther      = "other"
+)
+
+// ErrorClass groups an error returned by the adapter into a coarse class
+// suitable for metrics labels. It returns "" for a nil error.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	if isConnectionError(err) {
+		return ErrorClassConnection
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+	if errors.Is(err, adapter.ErrValidation) {
+		return ErrorClassValidation
+	}
+
+	var mysqlErr *gomysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1205, 3024: // lock wait timeout, max_execution_time exceeded
+			return ErrorClassTimeout
+		case 1213:
+			return ErrorClassDeadlock
+		case 1048, 1062, 1264, 1265, 1364, 1406, 1451, 1452, 3819:
+			return ErrorClassConstraint
+		case 1054, 1064, 1146, 1149:
+			return ErrorClassSyntax
+		}
+		return ErrorClassOther
+	}
+
+	var adapterErr *adapter.AdapterError
+	if errors.As(err, &adapterErr) {
+		switch adapterErr.Code {
+		case "CONNECTION":
+			return ErrorClassConnection
+		case "CONFLICT":
+			return ErrorClassConflict
+		case "NOT_FOUND":
+			return ErrorClassNotFound
+		case "VALIDATION":
+			return ErrorClassValidation
+		}
+	}
+	return ErrorClassOther
+}
+
+// Stats reports the adapter's connection pool statistics and operation
+// counters.
+type Stats struct {
+	// DBStats holds the primary pool statistics.
+	sql.DBStats
+
+	// Replicas holds the pool statistics of each replica by name.
+	Replicas map[string]sql.DBStats
+
+	// Queries is the number of operations run against the database.
+	Queries uint64
+
+	// Errors counts failed operations by ErrorClass.
+	Errors map[string]uint64
+
+	// Retries is the number of statements database/sql retried on another
+	// connection after the driver reported a bad connection.
+	Retries uint64
+
+	// SlowQueries is the number of statements that exceeded the slow query
+	// threshold.
+	SlowQueries uint64
+
+	// Breaker is the circuit breaker state.
+	Breaker BreakerState
+}
+
+// counters holds the adapter-level operation counters.
+type counters struct {
+	queries     atomic.Uint64
+	retries     atomic.Uint64
+	slowQueries atomic.Uint64
+
+	mu     sync.Mutex
+	errors map[string]uint64
+}
+
+// record counts an operation and its error, if any.
+func (c *counters) record(err error) {
+	c.queries.Add(1)
+	if err == nil {
+		return
+	}
+	class := ErrorClass(err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.errors == nil {
+		c.errors = make(map[string]uint64)
+	}
+	c.errors[class]++
+}
+
+// retried counts a statement retried after a bad connection.
+func (c *counters) retried() {
+	c.retries.Add(1)
+}
+
+// errorCounts returns a copy of the error counters.
+func (c *counters) errorCounts() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]uint64, len(c.errors))
+	for class, n := range c.errors {
+		counts[class] = n
+	}
+	return counts
+}
+
+// finish records the outcome of an operation let through by checkReady.
+func (a *MySQLAdapter) finish(err error) {
+	a.breaker.record(err)
+	a.stats.record(err)
+}
+
+// Stats returns the connection pool statistics and operation counters.
+func (a *MySQLAdapter) Stats() Stats {
+	s := Stats{
+		Queries:     a.stats.queries.Load(),
+		Errors:      a.stats.errorCounts(),
+		Retries:     a.stats.retries.Load(),
+		SlowQueries: a.stats.slowQueries.Load(),
+		Breaker:     a.BreakerState(),
+	}
+	if a.db != nil {
+		s.DBStats = a.db.Stats()
+	}
+	if a.replicas != nil {
+		s.Replicas = make(map[string]sql.DBStats, len(a.replicas.replicas))
+		for _, r := range a.replicas.replicas {
+			s.Replicas[r.name] = r.db.Stats()
+		}
+	}
+	return s
+}
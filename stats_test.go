@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{errRefused, ErrorClassConnection},
+		{ErrCircuitOpen, ErrorClassConnection},
+		{fmt.Errorf("mysql: query failed: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{context.Canceled, ErrorClassCanceled},
+		{&gomysql.MySQLError{Number: 1205}, ErrorClassTimeout},
+		{&gomysql.MySQLError{Number: 1213}, ErrorClassDeadlock},
+		{fmt.Errorf("mysql: insert failed: %w", &gomysql.MySQLError{Number: 1062}), ErrorClassConstraint},
+		{&gomysql.MySQLError{Number: 1064}, ErrorClassSyntax},
+		{&gomysql.MySQLError{Number: 1290}, ErrorClassOther},
+		{ErrConcurrentModification, ErrorClassConflict},
+		{adapter.ErrNotFound, ErrorClassNotFound},
+		{&ValidationError{Table: "users"}, ErrorClassValidation},
+		{errors.New("boom"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestMySQLAdapter_Stats(t *testing.T) {
+	a := NewMySQLAdapter()
+	a.db = sql.OpenDB(&countingConnector{})
+	defer func() { _ = a.db.Close() }()
+
+	op := &adapter.Operation{Statement: "SELECT 1"}
+	_, _ = a.Fetch(context.Background(), op, nil)
+	_ = a.Insert(context.Background(), op, nil)
+
+	s := a.Stats()
+	if s.Queries != 2 {
+		t.Errorf("expected 2 queries, got %d", s.Queries)
+	}
+	if s.Errors[ErrorClassConnection] != 1 || len(s.Errors) != 1 {
+		t.Errorf("expected one connection error, got %v", s.Errors)
+	}
+	if s.Breaker != BreakerClosed || s.Replicas != nil {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	s.Errors[ErrorClassOther] = 5
+	if a.Stats().Errors[ErrorClassOther] != 0 {
+		t.Error("expected a copy of the error counters")
+	}
+}
+
+func TestConnector_Retries(t *testing.T) {
+	var retries counters
+	c := &connector{base: &fakeConnector{execErr: driver.ErrBadConn}, onBadConn: retries.retried}
+	db := sql.OpenDB(c)
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.ExecContext(context.Background(), "DO 1"); !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected bad connection error, got %v", err)
+	}
+	if retries.retries.Load() < 2 {
+		t.Errorf("expected retries to be counted, got %d", retries.retries.Load())
+	}
+}
@@ -0,0 +1,212 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// killTimeout bounds opening a connection and running KILL QUERY.
+const killTimeout = 5 * time.Second
+
+// queryTimeout returns the timeout of operations on mapping: its entry in
+// QueryTimeouts, or QueryTimeout.
+func (a *MySQLAdapter) queryTimeout(mapping string) time.Duration {
+	if d, ok := a.config.QueryTimeouts[mapping]; ok {
+		return d
+	}
+	return a.config.QueryTimeout
+}
+
+// maxExecutionTime adds a MAX_EXECUTION_TIME optimizer hint for d to a
+// SELECT statement, so the server stops it even if the client goes away.
+// Other statements and statements that already carry hints are returned as
+// they are.
+func maxExecutionTime(query string, d time.Duration) string {
+	if d <= 0 || statementVerb(query) != "SELECT" || strings.Contains(query, "/*+") {
+		return query
+	}
+
+	// The hint goes right after the verb, matched on the statement itself
+	// since upper-casing can change byte offsets
+	i := len(query) - len(strings.TrimLeftFunc(query, unicode.IsSpace)) + len("SELECT")
+	if i > len(query) || !strings.EqualFold(query[i-len("SELECT"):i], "SELECT") {
+		return query
+	}
+	ms := (d + time.Millisecond - 1) / time.Millisecond
+	return query[:i] + " /*+ MAX_EXECUTION_TIME(" + strconv.FormatInt(int64(ms), 10) + ") */" + query[i:]
+}
+
+// connectionID returns the server thread ID of a driver connection.
+func connectionID(ctx context.Context, conn driver.Conn) (uint64, error) {
+	q, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return 0, fmt.Errorf("mysql: connection cannot run queries")
+	}
+	rows, err := q.QueryContext(ctx, "SELECT CONNECTION_ID()", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			return 0, fmt.Errorf("mysql: no connection ID returned")
+		}
+		return 0, err
+	}
+	switch id := dest[0].(type) {
+	case int64:
+		return uint64(id), nil
+	case uint64:
+		return id, nil
+	case []byte:
+		return strconv.ParseUint(string(id), 10, 64)
+	}
+	return 0, fmt.Errorf("mysql: unexpected connection ID %T", dest[0])
+}
+
+// killQuery stops the statement running on thread id of the server at addr
+// (the configured address when empty) from a connection of its own.
+func (c *connector) killQuery(addr string, id uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+
+	conn, err := c.openAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	return execConn(ctx, conn, "KILL QUERY "+strconv.FormatUint(id, 10))
+}
+
+// killIfCanceled kills the statement left running on the server when its
+// context ended while it ran. The driver only closes its side of the
+// connection, so the server would otherwise run the statement to completion.
+// A context that had already ended when the statement was sent (live is
+// false) leaves nothing running: the driver returns before sending it.
+func (c *pooledConn) killIfCanceled(ctx context.Context, live bool, err error) {
+	if !live || err == nil || ctx.Err() == nil {
+		return
+	}
+	c.killStatement()
+}
+
+// killStatement issues KILL QUERY for the connection's thread once, marking
+// the connection killed first so the pool never hands it out again while the
+// KILL is on its way.
+func (c *pooledConn) killStatement() {
+	if c.kill == nil || c.threadID == 0 || !c.killed.CompareAndSwap(false, true) {
+		return
+	}
+	go c.kill(c.threadID)
+}
+
+// watchRows returns rows that kill the statement still producing them when
+// its context ends before they are all read.
+func (c *pooledConn) watchRows(ctx context.Context, rows driver.Rows) driver.Rows {
+	if c.kill == nil || c.threadID == 0 || rows == nil {
+		return rows
+	}
+	return &killRows{Rows: rows, ctx: ctx, conn: c}
+}
+
+// killRows is a result set of a connection that kills its statement on
+// cancellation. database/sql serializes Next and Close, including the Close
+// it issues when the context ends.
+type killRows struct {
+	driver.Rows
+	ctx  context.Context
+	conn *pooledConn
+	done bool
+}
+
+// Next implements driver.Rows.
+func (r *killRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == io.EOF:
+		r.done = true
+	case err != nil:
+		r.kill()
+	}
+	return err
+}
+
+// Close implements driver.Rows.
+func (r *killRows) Close() error {
+	if !r.done {
+		r.kill()
+	}
+	return r.Rows.Close()
+}
+
+// kill kills the statement if the context ended before it was read to the end.
+func (r *killRows) kill() {
+	if r.ctx.Err() == nil {
+		return
+	}
+	r.conn.killStatement()
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet.
+func (r *killRows) HasNextResultSet() bool {
+	n, ok := r.Rows.(driver.RowsNextResultSet)
+	return ok && n.HasNextResultSet()
+}
+
+// NextResultSet implements driver.RowsNextResultSet.
+func (r *killRows) NextResultSet() error {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		r.done = false
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType.
+func (r *killRows) ColumnTypeScanType(index int) reflect.Type {
+	if t, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return t.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName.
+func (r *killRows) ColumnTypeDatabaseTypeName(index int) string {
+	if t, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return t.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable.
+func (r *killRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return t.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypeLength implements driver.RowsColumnTypeLength.
+func (r *killRows) ColumnTypeLength(index int) (length int64, ok bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return t.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypePrecisionScale implements driver.RowsColumnTypePrecisionScale.
+func (r *killRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return t.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,95 @@
+//go:build integration
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// integrationDSN returns the DSN of the test server, by default the one
+// started by docker-compose.
+func integrationDSN() string {
+	if dsn := os.Getenv("MYSQL_TEST_DSN"); dsn != "" {
+		return dsn
+	}
+	return "testuser:testpass@tcp(localhost:3306)/example_db"
+}
+
+// connectIntegration connects an adapter to the test server with config, and
+// a plain pool for inspecting the server, skipping when it is unreachable.
+func connectIntegration(t *testing.T, config map[string]interface{}) (*MySQLAdapter, *sql.DB) {
+	t.Helper()
+	observer, err := sql.Open("mysql", integrationDSN())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = observer.Close() })
+	if err := observer.Ping(); err != nil {
+		t.Skipf("MySQL not reachable: %v", err)
+	}
+
+	a := NewMySQLAdapter()
+	config[ConfigDSN] = integrationDSN()
+	if err := a.Connect(context.Background(), config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = a.Close() })
+	return a, observer
+}
+
+// waitGone fails the test unless no statement starting with query is running
+// on the server within a few seconds.
+func waitGone(t *testing.T, db *sql.DB, query string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var n int
+		err := db.QueryRow(
+			"SELECT COUNT(*) FROM information_schema.PROCESSLIST WHERE INFO LIKE ?", query+"%",
+		).Scan(&n)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%q still running on the server", query)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestIntegration_KillOnCancel(t *testing.T) {
+	a, observer := connectIntegration(t, map[string]interface{}{"kill_on_cancel": true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	action := &adapter.Action{Name: "sleep", Statement: "SELECT SLEEP(30)", Result: &adapter.ResultMapping{}}
+	if _, err := a.Execute(ctx, action, nil); err == nil {
+		t.Fatal("expected the canceled statement to fail")
+	}
+
+	waitGone(t, observer, "SELECT SLEEP(30)")
+}
+
+func TestIntegration_MaxExecutionTime(t *testing.T) {
+	a, observer := connectIntegration(t, map[string]interface{}{
+		"query_timeouts": map[string]interface{}{"sleep": "500ms"},
+	})
+
+	start := time.Now()
+	action := &adapter.Action{Name: "sleep", Statement: "SELECT SLEEP(30)", Result: &adapter.ResultMapping{}}
+	_, _ = a.Execute(context.Background(), action, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the statement to stop after its timeout, took %s", elapsed)
+	}
+
+	waitGone(t, observer, "SELECT /*+ MAX_EXECUTION_TIME(500) */ SLEEP(30)")
+}
@@ -0,0 +1,282 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// sleepConnector hands out connections with thread IDs whose SLEEP
+// statements block until their context ends, and reports KILL QUERY.
+type sleepConnector struct {
+	mu     sync.Mutex
+	nextID int64
+	killed chan string
+}
+
+func (c *sleepConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	return &sleepConn{id: c.nextID, connector: c}, nil
+}
+
+func (c *sleepConnector) Driver() driver.Driver {
+	return nil
+}
+
+// sleepConn is a connection of a sleepConnector.
+type sleepConn struct {
+	id        int64
+	connector *sleepConnector
+}
+
+func (c *sleepConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *sleepConn) Close() error {
+	return nil
+}
+
+func (c *sleepConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *sleepConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch query {
+	case "SELECT CONNECTION_ID()":
+		return &fakeRows{columns: []string{"CONNECTION_ID()"}, rows: [][]driver.Value{{c.id}}}, nil
+	case "SELECT SLEEP(10)":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "SELECT id FROM events":
+		return &streamRows{ctx: ctx}, nil
+	}
+	return nil, fmt.Errorf("unexpected query %q", query)
+}
+
+// streamRows returns one row and then blocks until its context ends, like
+// a result set the server is still producing.
+type streamRows struct {
+	ctx  context.Context
+	sent bool
+}
+
+func (r *streamRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (r *streamRows) Close() error {
+	return nil
+}
+
+func (r *streamRows) Next(dest []driver.Value) error {
+	if !r.sent {
+		r.sent = true
+		dest[0] = int64(1)
+		return nil
+	}
+	<-r.ctx.Done()
+	return r.ctx.Err()
+}
+
+func (c *sleepConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.connector.killed <- query
+	return driver.RowsAffected(0), nil
+}
+
+func TestConnector_KillOnCancel(t *testing.T) {
+	base := &sleepConnector{killed: make(chan string, 1)}
+	db := sql.OpenDB(&connector{base: base, killOnCancel: true})
+	defer func() { _ = db.Close() }()
+
+	// Statements that finish are left alone
+	var id int64
+	if err := db.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&id); err != nil || id != 1 {
+		t.Fatalf("unexpected connection ID %d (%v)", id, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.QueryContext(ctx, "SELECT SLEEP(10)"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+
+	select {
+	case query := <-base.killed:
+		if query != "KILL QUERY 1" {
+			t.Errorf("expected the statement's thread to be killed, got %q", query)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected KILL QUERY after cancellation")
+	}
+
+	// The killed connection is not handed out again
+	if err := db.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&id); err != nil || id == 1 {
+		t.Errorf("expected a new connection after the kill, got %d (%v)", id, err)
+	}
+}
+
+func TestConnector_KillOnlyLiveStatements(t *testing.T) {
+	base := &sleepConnector{killed: make(chan string, 1)}
+	dc, err := (&connector{base: base, killOnCancel: true}).Connect(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn := dc.(*pooledConn)
+	kills := make(chan uint64, 2)
+	conn.kill = func(id uint64) { kills <- id }
+
+	// A context that ended before the statement was sent leaves nothing to kill
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conn.QueryContext(ctx, "SELECT SLEEP(10)", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	rows, err := conn.QueryContext(context.Background(), "SELECT CONNECTION_ID()", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !conn.IsValid() || conn.ResetSession(context.Background()) != nil {
+		t.Error("expected the connection to stay valid")
+	}
+	select {
+	case id := <-kills:
+		t.Errorf("expected no kill for a statement that was never sent, got %d", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once a kill is issued the pool must discard the connection
+	conn.killStatement()
+	conn.killStatement()
+	if conn.IsValid() || !errors.Is(conn.ResetSession(context.Background()), driver.ErrBadConn) {
+		t.Error("expected the killed connection to be invalid")
+	}
+	if id := <-kills; id != 1 {
+		t.Errorf("expected thread 1 to be killed, got %d", id)
+	}
+	select {
+	case id := <-kills:
+		t.Errorf("expected a single kill, got another of %d", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestConnector_KillOnCancelWhileReading(t *testing.T) {
+	base := &sleepConnector{killed: make(chan string, 1)}
+	db := sql.OpenDB(&connector{base: base, killOnCancel: true})
+	defer func() { _ = db.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT id FROM events")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("expected a first row, got %v", rows.Err())
+	}
+	cancel()
+	for rows.Next() {
+	}
+	_ = rows.Close()
+
+	select {
+	case query := <-base.killed:
+		if query != "KILL QUERY 1" {
+			t.Errorf("expected the statement's thread to be killed, got %q", query)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected KILL QUERY after cancellation while reading")
+	}
+}
+
+func TestMySQLAdapter_QueryTimeout(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"SELECT /*+ MAX_EXECUTION_TIME(1500) */ id FROM users": {columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}},
+		"DELETE FROM users WHERE id = ?":                       {},
+	}}
+	var remaining []time.Duration
+	a, err := NewMySQLAdapterWithOptions(
+		WithQueryTimeout(time.Minute),
+		WithMappingTimeout("active_ids", 1500*time.Millisecond),
+		WithInterceptors(func(ctx context.Context, call *Call, next Handler) (*Result, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Errorf("expected a deadline for %s", call.SQL)
+			}
+			remaining = append(remaining, time.Until(deadline))
+			return next(ctx, call)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+
+	action := &adapter.Action{Name: "active_ids", Statement: "SELECT id FROM users", Result: &adapter.ResultMapping{}}
+	if _, err := a.Execute(context.Background(), action, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	op := &adapter.Operation{Statement: "users", Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}}}
+	if err := a.Delete(context.Background(), op, []interface{}{1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(remaining) != 2 || remaining[0] > 1500*time.Millisecond || remaining[1] <= 1500*time.Millisecond {
+		t.Errorf("expected mapping and default timeouts, got %v", remaining)
+	}
+}
+
+func TestMaxExecutionTime(t *testing.T) {
+	tests := []struct {
+		query string
+		d     time.Duration
+		want  string
+	}{
+		{"SELECT * FROM users", 2 * time.Second, "SELECT /*+ MAX_EXECUTION_TIME(2000) */ * FROM users"},
+		{"  select id from users", 1500 * time.Microsecond, "  select /*+ MAX_EXECUTION_TIME(2) */ id from users"},
+		{"\u00a0SeLeCt 'ſ' FROM t1", time.Second, "\u00a0SeLeCt /*+ MAX_EXECUTION_TIME(1000) */ 'ſ' FROM t1"},
+		{"ſelect id FROM t1", time.Second, "ſelect id FROM t1"},
+		{"SELECT /*+ BKA(t1) */ * FROM t1", time.Second, "SELECT /*+ BKA(t1) */ * FROM t1"},
+		{"UPDATE users SET name = ?", time.Second, "UPDATE users SET name = ?"},
+		{"SELECT * FROM users", 0, "SELECT * FROM users"},
+	}
+	for _, tt := range tests {
+		if got := maxExecutionTime(tt.query, tt.d); got != tt.want {
+			t.Errorf("maxExecutionTime(%q, %s) = %q, want %q", tt.query, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_QueryTimeouts(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"query_timeout_seconds": 30,
+		"query_timeouts":        map[string]interface{}{"reports": "2m", "users": 1.5},
+		"kill_on_cancel":        true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.QueryTimeout != 30*time.Second || cfg.QueryTimeouts["reports"] != 2*time.Minute || cfg.QueryTimeouts["users"] != 1500*time.Millisecond || !cfg.KillOnCancel {
+		t.Errorf("unexpected timeouts %+v %v", cfg.QueryTimeout, cfg.QueryTimeouts)
+	}
+
+	cfg.QueryTimeouts["reports"] = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "query_timeouts.reports") {
+		t.Errorf("expected negative mapping timeout to be rejected, got %v", err)
+	}
+}
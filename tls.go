@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// TLS verification modes.
+const (
+	// TLSVerifyFull verifies the server certificate chain and hostname.
+	TLSVerifyFull = "full"
+
+	// TLSVerifyCA verifies the server certificate chain but not the hostname.
+	TLSVerifyCA = "ca"
+
+	// TLSVerifySkip accepts any server certificate. Use only for testing.
+	TLSVerifySkip = "skip"
+)
+
+// TLSConfig describes a custom TLS setup for connections to MySQL.
+// When any field is set it replaces the ssl mode.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of certificate authorities trusted to sign the
+	// server certificate. The system pool is used when empty.
+	CAFile string
+
+	// CertFile and KeyFile hold a PEM client certificate and private key
+	// presented to the server.
+	CertFile string
+	KeyFile  string
+
+	// ServerName overrides the hostname checked against the server
+	// certificate. It defaults to the connection host.
+	ServerName string
+
+	// MinVersion is the minimum TLS version (1.0, 1.1, 1.2, 1.3). Defaults to 1.2.
+	MinVersion string
+
+	// Verify is the verification mode: full (default), ca or skip.
+	Verify string
+}
+
+// enabled reports whether a custom TLS configuration was requested.
+func (t TLSConfig) enabled() bool {
+	return t != TLSConfig{}
+}
+
+// build loads certificates and returns the *tls.Config for serverName.
+func (t TLSConfig) build(serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: serverName}
+	if t.ServerName != "" {
+		cfg.ServerName = t.ServerName
+	}
+
+	version, err := tlsVersion(t.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MinVersion = version
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_ca_file: no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, errors.New("tls_cert_file and tls_key_file must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_cert_file: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch t.Verify {
+	case "", TLSVerifyFull:
+	case TLSVerifyCA:
+		// Skip the built-in verification, which always checks the hostname,
+		// and verify the chain against the trusted roots ourselves
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyChain(cfg.RootCAs)
+	case TLSVerifySkip:
+		cfg.InsecureSkipVerify = true
+	default:
+		return nil, fmt.Errorf("tls_verify must be one of %s, %s, %s, got %q", TLSVerifyFull, TLSVerifyCA, TLSVerifySkip, t.Verify)
+	}
+
+	return cfg, nil
+}
+
+// verifyChain returns a callback verifying the peer certificate chain
+// against roots without checking the hostname.
+func verifyChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("mysql: server presented no certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("mysql: invalid server certificate: %w", err)
+			}
+			certs[i] = cert
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+}
+
+// tlsVersion parses a TLS version name.
+func tlsVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("tls_min_version must be one of 1.0, 1.1, 1.2, 1.3, got %q", v)
+}
+
+// tlsConfigSeq numbers the TLS configurations registered with the driver.
+var tlsConfigSeq atomic.Uint64
+
+// registerTLS builds the adapter's TLS configuration and registers it with
+// the driver under a name unique to this adapter, returning the name.
+func registerTLS(t TLSConfig, addr string) (string, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	cfg, err := t.build(host)
+	if err != nil {
+		return "", fmt.Errorf("mysql: invalid tls configuration: %w", err)
+	}
+
+	name := "toutago-mysql-" + strconv.FormatUint(tlsConfigSeq.Add(1), 10)
+	if err := gomysql.RegisterTLSConfig(name, cfg); err != nil {
+		return "", fmt.Errorf("mysql: failed to register tls configuration: %w", err)
+	}
+	return name, nil
+}
@@ -0,0 +1,269 @@
+package mysql
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// testPKI is a throwaway certificate authority with a server and client certificate.
+type testPKI struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverCert tls.Certificate
+}
+
+func newTestPKI(t *testing.T, serverName string) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey := newKey(t)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, tmpl *x509.Certificate) ([]byte, *ecdsa.PrivateKey) {
+		key := newKey(t)
+		tmpl.SerialNumber = big.NewInt(serial)
+		tmpl.NotBefore = time.Now().Add(-time.Hour)
+		tmpl.NotAfter = time.Now().Add(time.Hour)
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		return der, key
+	}
+
+	serverDER, serverKey := issue(2, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: serverName},
+		DNSNames:    []string{serverName},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	clientDER, clientKey := issue(3, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "app"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	p := &testPKI{
+		caFile:   writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER),
+		certFile: writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER),
+		keyFile:  writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", marshalKey(t, clientKey)),
+	}
+	p.serverCert = tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}
+	return p
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func marshalKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return der
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// handshake runs a TLS handshake between client and a server presenting cert.
+func handshake(client *tls.Config, cert tls.Certificate) error {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", ln.Addr().String(), client)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestTLSConfig_Build(t *testing.T) {
+	pki := newTestPKI(t, "db.internal")
+
+	cfg, err := TLSConfig{
+		CAFile:     pki.caFile,
+		CertFile:   pki.certFile,
+		KeyFile:    pki.keyFile,
+		MinVersion: "1.3",
+	}.build("db.internal")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.RootCAs == nil {
+		t.Error("expected CA pool to be loaded")
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Errorf("expected client certificate, got %d certificates", len(cfg.Certificates))
+	}
+	if cfg.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected TLS 1.3 minimum, got %x", cfg.MinVersion)
+	}
+	if cfg.ServerName != "db.internal" || cfg.InsecureSkipVerify {
+		t.Errorf("expected full verification of db.internal, got %+v", cfg)
+	}
+}
+
+func TestTLSConfig_BuildErrors(t *testing.T) {
+	pki := newTestPKI(t, "db.internal")
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+	}{
+		{name: "missing CA file", cfg: TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{name: "CA file without certificates", cfg: TLSConfig{CAFile: pki.keyFile}},
+		{name: "certificate without key", cfg: TLSConfig{CertFile: pki.certFile}},
+		{name: "unknown version", cfg: TLSConfig{MinVersion: "2.0"}},
+		{name: "unknown verify mode", cfg: TLSConfig{Verify: "sometimes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cfg.build("db.internal"); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestTLSConfig_VerifyModes(t *testing.T) {
+	pki := newTestPKI(t, "db.internal")
+	other := newTestPKI(t, "db.internal")
+
+	tests := []struct {
+		name       string
+		verify     string
+		serverName string
+		cert       tls.Certificate
+		wantErr    bool
+	}{
+		{name: "full with matching host", verify: TLSVerifyFull, serverName: "db.internal", cert: pki.serverCert},
+		{name: "full with wrong host", verify: TLSVerifyFull, serverName: "10.0.0.5", cert: pki.serverCert, wantErr: true},
+		{name: "ca with wrong host", verify: TLSVerifyCA, serverName: "10.0.0.5", cert: pki.serverCert},
+		{name: "ca with untrusted certificate", verify: TLSVerifyCA, serverName: "db.internal", cert: other.serverCert, wantErr: true},
+		{name: "skip with untrusted certificate", verify: TLSVerifySkip, serverName: "db.internal", cert: other.serverCert},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := TLSConfig{CAFile: pki.caFile, Verify: tt.verify}.build(tt.serverName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = handshake(cfg, tt.cert)
+			if tt.wantErr && err == nil {
+				t.Error("expected handshake to fail")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected handshake error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterTLS(t *testing.T) {
+	pki := newTestPKI(t, "db.internal")
+	tlsCfg := TLSConfig{CAFile: pki.caFile}
+
+	first, err := registerTLS(tlsCfg, "db.internal:3306")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer gomysql.DeregisterTLSConfig(first)
+
+	second, err := registerTLS(tlsCfg, "db.internal:3306")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer gomysql.DeregisterTLSConfig(second)
+
+	if first == second {
+		t.Errorf("expected unique names per registration, got '%s' twice", first)
+	}
+
+	// The driver must accept the registered name in a DSN
+	if _, err := gomysql.ParseDSN("root@tcp(db.internal:3306)/?tls=" + first); err != nil {
+		t.Errorf("expected registered TLS config to be usable, got %v", err)
+	}
+}
+
+func TestConfig_ApplyTLS(t *testing.T) {
+	pki := newTestPKI(t, "db.internal")
+
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"tls_ca_file":     pki.caFile,
+		"tls_cert_file":   pki.certFile,
+		"tls_key_file":    pki.keyFile,
+		"tls_server_name": "db.internal",
+		"tls_min_version": 1.2,
+		"tls_verify":      "ca",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TLS.MinVersion != "1.2" || cfg.TLS.Verify != "ca" || cfg.TLS.CAFile != pki.caFile {
+		t.Errorf("unexpected TLS config %+v", cfg.TLS)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	cfg.TLS.CertFile = filepath.Join(t.TempDir(), "missing.pem")
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error for missing certificate")
+	}
+}
@@ -0,0 +1,226 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the adapter's spans.
+const tracerName = "github.com/toutaio/toutago-datamapper-mysql"
+
+// Span attributes not covered by the semantic conventions.
+const (
+	attrRowsReturned = attribute.Key("db.response.returned_rows")
+	attrRowsAffected = attribute.Key("db.rows_affected")
+	attrAction       = attribute.Key("mysql.action")
+	attrRetried      = attribute.Key("mysql.retried")
+)
+
+// tracing creates spans for adapter operations and the statements they run.
+type tracing struct {
+	tracer trace.Tracer
+	dbName string
+}
+
+// newTracing returns tracing through tp, or nil if tp is nil.
+func newTracing(tp trace.TracerProvider, dbName string) *tracing {
+	if tp == nil {
+		return nil
+	}
+	return &tracing{tracer: tp.Tracer(tracerName), dbName: dbName}
+}
+
+// attributes returns the attributes common to every span.
+func (t *tracing) attributes(extra ...attribute.KeyValue) []attribute.KeyValue {
+	attrs := append([]attribute.KeyValue{semconv.DBSystemMySQL}, extra...)
+	if t.dbName != "" {
+		attrs = append(attrs, semconv.DBName(t.dbName))
+	}
+	return attrs
+}
+
+// startOperation starts the span of an adapter method. Without tracing it
+// returns a span that records nothing.
+func (t *tracing) startOperation(ctx context.Context, method, kind string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, noop.Span{}
+	}
+	return t.tracer.Start(ctx, "mysql."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(t.attributes(append(attrs, semconv.DBOperation(kind))...)...))
+}
+
+// endOperation ends the span of an adapter method.
+func endOperation(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	recordError(span, err)
+	span.End()
+}
+
+// statement records a client span for a statement that ran on a connection
+// to addr from start until now. Statements the driver skipped are not
+// recorded, since database/sql runs them again as prepared statements.
+func (t *tracing) statement(ctx context.Context, start time.Time, addr, query string, res driver.Result, err error) {
+	if t == nil || errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	attrs := t.attributes(
+		semconv.DBOperation(statementVerb(query)),
+		semconv.DBStatement(sanitizeSQL(query)),
+	)
+	attrs = append(attrs, serverAttributes(addr)...)
+	_, span := t.tracer.Start(ctx, spanName(query, t.dbName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...))
+
+	if res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			span.SetAttributes(attrRowsAffected.Int64(n))
+		}
+	}
+	// database/sql retries the statement on another connection
+	if errors.Is(err, driver.ErrBadConn) {
+		span.SetAttributes(attrRetried.Bool(true))
+	}
+	recordError(span, err)
+	span.End()
+}
+
+// recordError marks span as failed with err, if any.
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// serverAttributes returns the server address attributes for addr.
+func serverAttributes(addr string) []attribute.KeyValue {
+	if addr == "" {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// Unix socket path
+		return []attribute.KeyValue{semconv.ServerAddress(addr)}
+	}
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
+	return attrs
+}
+
+// spanName returns the span name of a statement: its verb and database.
+func spanName(query, dbName string) string {
+	verb := statementVerb(query)
+	if dbName == "" {
+		return verb
+	}
+	return verb + " " + dbName
+}
+
+// statementVerb returns the upper-cased first keyword of a statement.
+func statementVerb(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// sanitizeSQL replaces string and numeric literals in query with ? so
+// statement attributes never carry values.
+func sanitizeSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			// Skip to the closing quote, honoring backslash and doubled quotes
+			j := i + 1
+			for j < len(query) {
+				if query[j] == '\\' {
+					j += 2
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			b.WriteByte('?')
+			i = j
+		case c == '`':
+			// Quoted identifiers are kept as they are
+			j := strings.IndexByte(query[i+1:], '`')
+			if j < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+j+2])
+			i += j + 1
+		case isDigit(c) && (i == 0 || !isIdentByte(query[i-1])):
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+				j++
+			}
+			b.WriteByte('?')
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentByte reports whether c can be part of an unquoted identifier.
+func isIdentByte(c byte) bool {
+	return isDigit(c) || c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// pooledTx wraps a transaction so its commit or rollback is traced.
+type pooledTx struct {
+	driver.Tx
+	ctx  context.Context
+	conn *pooledConn
+}
+
+// Commit implements driver.Tx.
+func (t *pooledTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	t.conn.tracing.statement(t.ctx, start, t.conn.addr, "COMMIT", nil, err)
+	return err
+}
+
+// Rollback implements driver.Tx.
+func (t *pooledTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	t.conn.tracing.statement(t.ctx, start, t.conn.addr, "ROLLBACK", nil, err)
+	return err
+}
@@ -0,0 +1,146 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/toutaio/toutago-datamapper/adapter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedAdapter returns an adapter whose statements run on base and are
+// traced into the returned exporter.
+func tracedAdapter(t *testing.T, base driver.Connector) (*MySQLAdapter, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	a := NewMySQLAdapter()
+	a.tracing = newTracing(tp, "app")
+	a.db = sql.OpenDB(&connector{
+		base:      base,
+		cfg:       &gomysql.Config{Addr: "db.internal:3306", DBName: "app"},
+		onBadConn: a.stats.retried,
+		tracing:   a.tracing,
+	})
+	t.Cleanup(func() { _ = a.db.Close() })
+	return a, exporter
+}
+
+// spanAttr returns the value of key on span.
+func spanAttr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_Operation(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"DELETE FROM sessions WHERE created < '2020-01-01' AND id = ?": {},
+	}}
+	a, exporter := tracedAdapter(t, script)
+
+	action := &adapter.Action{Name: "purge", Statement: "DELETE FROM sessions WHERE created < '2020-01-01' AND id = {id}"}
+	if _, err := a.Execute(context.Background(), action, map[string]interface{}{"id": 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected statement and operation spans, got %d", len(spans))
+	}
+	stmt, op := spans[0], spans[1]
+	if op.Name != "mysql.Execute" || spanAttr(op, "mysql.action").AsString() != "purge" || spanAttr(op, "db.operation").AsString() != "action" {
+		t.Errorf("unexpected operation span %s %v", op.Name, op.Attributes)
+	}
+	if stmt.Name != "DELETE app" || stmt.SpanKind != trace.SpanKindClient || stmt.Parent.SpanID() != op.SpanContext.SpanID() {
+		t.Errorf("expected client statement span under the operation, got %s", stmt.Name)
+	}
+	want := map[attribute.Key]string{
+		"db.system":      "mysql",
+		"db.name":        "app",
+		"db.operation":   "DELETE",
+		"db.statement":   "DELETE FROM sessions WHERE created < ? AND id = ?",
+		"server.address": "db.internal",
+	}
+	for key, value := range want {
+		if got := spanAttr(stmt, key).AsString(); got != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got)
+		}
+	}
+	if spanAttr(stmt, "server.port").AsInt64() != 3306 || spanAttr(stmt, "db.rows_affected").AsInt64() != 1 {
+		t.Errorf("unexpected statement attributes %v", stmt.Attributes)
+	}
+}
+
+func TestTracing_Errors(t *testing.T) {
+	a, exporter := tracedAdapter(t, &fakeConnector{execErr: driver.ErrBadConn})
+
+	action := &adapter.Action{Name: "touch", Statement: "UPDATE users SET seen = NOW()"}
+	if _, err := a.Execute(context.Background(), action, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	spans := exporter.GetSpans()
+	op := spans[len(spans)-1]
+	if op.Name != "mysql.Execute" || op.Status.Code != codes.Error || len(op.Events) == 0 {
+		t.Errorf("expected failed operation span with recorded error, got %+v", op.Status)
+	}
+	attempts := spans[:len(spans)-1]
+	if len(attempts) < 2 {
+		t.Fatalf("expected a span per attempt, got %d", len(attempts))
+	}
+	for _, s := range attempts {
+		if !spanAttr(s, "mysql.retried").AsBool() || s.Parent.SpanID() != op.SpanContext.SpanID() {
+			t.Errorf("expected retried attempt under the operation, got %s %v", s.Name, s.Attributes)
+		}
+	}
+	if a.Stats().Retries != uint64(len(attempts)) {
+		t.Errorf("expected %d retries, got %d", len(attempts), a.Stats().Retries)
+	}
+}
+
+func TestTracing_Transaction(t *testing.T) {
+	a, exporter := tracedAdapter(t, &scriptConnector{})
+
+	tx, err := a.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "BEGIN app" || spans[1].Name != "COMMIT app" {
+		t.Errorf("expected BEGIN and COMMIT spans, got %v", spans.Snapshots())
+	}
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM users WHERE id = ?", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT * FROM users WHERE name = 'O''Brien' AND age > 30", "SELECT * FROM users WHERE name = ? AND age > ?"},
+		{`UPDATE t SET note = "a \" b", score = 1.5`, "UPDATE t SET note = ?, score = ?"},
+		{"SELECT col1, `2019 totals` FROM t2 LIMIT 10", "SELECT col1, `2019 totals` FROM t2 LIMIT ?"},
+		{"SELECT 'unterminated", "SELECT ?"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeSQL(tt.query); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+}
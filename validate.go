@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// ValidationError reports generated DML that the adapter refused to execute
+// because it would be malformed or would touch every row of a table.
+// It matches adapter.ErrValidation with errors.Is.
+type ValidationError struct {
+	// Operation is the kind of statement that was being generated.
+	Operation adapter.OperationType
+
+	// Table is the mapping's target table (the operation statement).
+	Table string
+
+	// Reason describes what is wrong with the generated statement.
+	Reason string
+
+	// Fields lists the object fields that would have fixed the statement.
+	Fields []string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("mysql: invalid %s on %s: %s", e.Operation, e.Table, e.Reason)
+	if len(e.Fields) > 0 {
+		msg += " (expected one of: " + strings.Join(e.Fields, ", ") + ")"
+	}
+	return msg
+}
+
+// Unwrap allows errors.Is(err, adapter.ErrValidation) to match.
+func (e *ValidationError) Unwrap() error {
+	return adapter.ErrValidation
+}
+
+// validateInsert rejects an INSERT with no columns.
+func validateInsert(op *adapter.Operation, fields []string) error {
+	if len(fields) > 0 {
+		return nil
+	}
+	var expected []string
+	for _, prop := range op.Properties {
+		if !isGeneratedField(op, prop.DataField) {
+			expected = append(expected, prop.ObjectField)
+		}
+	}
+	return &ValidationError{
+		Operation: adapter.OpInsert,
+		Table:     op.Statement,
+		Reason:    "no columns to insert",
+		Fields:    expected,
+	}
+}
+
+// validateUpdate rejects an UPDATE with an empty SET clause or without
+// identifier clauses. Optimistic locking conditions do not count: a version
+// alone would match every row holding it.
+func validateUpdate(op *adapter.Operation, setClauses, idClauses []string) error {
+	if len(idClauses) == 0 {
+		return &ValidationError{
+			Operation: adapter.OpUpdate,
+			Table:     op.Statement,
+			Reason:    "no identifier fields configured, statement would update every row",
+		}
+	}
+	if len(setClauses) > 0 {
+		return nil
+	}
+	var expected []string
+	for _, prop := range op.Properties {
+		if !isIdentifierField(op, prop.DataField) && !isConditionField(op, prop.DataField) && !isGeneratedField(op, prop.DataField) {
+			expected = append(expected, prop.ObjectField)
+		}
+	}
+	return &ValidationError{
+		Operation: adapter.OpUpdate,
+		Table:     op.Statement,
+		Reason:    "no columns to set",
+		Fields:    expected,
+	}
+}
+
+// validateDelete rejects a DELETE with no WHERE clause.
+func validateDelete(op *adapter.Operation, whereClauses []string) error {
+	if len(whereClauses) > 0 {
+		return nil
+	}
+	return &ValidationError{
+		Operation: adapter.OpDelete,
+		Table:     op.Statement,
+		Reason:    "no identifier fields configured, statement would delete every row",
+	}
+}
+
+// isGeneratedField reports whether dataField is generated by the database.
+func isGeneratedField(op *adapter.Operation, dataField string) bool {
+	for _, gen := range op.Generated {
+		if gen.DataField == dataField {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+func TestValidateInsert(t *testing.T) {
+	op := &adapter.Operation{
+		Statement: "users",
+		Properties: []adapter.PropertyMapping{
+			{ObjectField: "ID", DataField: "id"},
+			{ObjectField: "Name", DataField: "name"},
+		},
+		Generated: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+	}
+
+	if err := validateInsert(op, []string{"name"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := validateInsert(op, nil)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if verr.Table != "users" || verr.Operation != adapter.OpInsert {
+		t.Errorf("unexpected error details %+v", verr)
+	}
+	if len(verr.Fields) != 1 || verr.Fields[0] != "Name" {
+		t.Errorf("expected missing fields [Name], got %v", verr.Fields)
+	}
+	if !errors.Is(err, adapter.ErrValidation) {
+		t.Error("expected error to match adapter.ErrValidation")
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	op := &adapter.Operation{
+		Statement: "users",
+		Properties: []adapter.PropertyMapping{
+			{ObjectField: "ID", DataField: "id"},
+			{ObjectField: "Email", DataField: "email"},
+			{ObjectField: "Version", DataField: "version"},
+		},
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Condition:  []adapter.PropertyMapping{{ObjectField: "Version", DataField: "version"}},
+	}
+
+	if err := validateUpdate(op, []string{"email = ?"}, []string{"id = ?"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := validateUpdate(op, nil, []string{"id = ?"})
+	if err == nil {
+		t.Fatal("expected error for empty SET clause")
+	}
+	if !strings.Contains(err.Error(), "no columns to set") || !strings.Contains(err.Error(), "Email") {
+		t.Errorf("unexpected message '%s'", err.Error())
+	}
+
+	err = validateUpdate(op, []string{"email = ?"}, nil)
+	if !errors.Is(err, adapter.ErrValidation) {
+		t.Errorf("expected validation error for missing WHERE clause, got %v", err)
+	}
+}
+
+func TestValidateDelete(t *testing.T) {
+	op := &adapter.Operation{Statement: "users"}
+
+	if err := validateDelete(op, []string{"id = ?"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateDelete(op, nil); !errors.Is(err, adapter.ErrValidation) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestMySQLAdapter_DeleteScalarIdentifier(t *testing.T) {
+	script := &scriptConnector{}
+	a := newScriptAdapter(t, script)
+
+	for _, identifiers := range [][]adapter.PropertyMapping{
+		nil,
+		{{ObjectField: "TenantID", DataField: "tenant_id"}, {ObjectField: "ID", DataField: "id"}},
+	} {
+		op := &adapter.Operation{Statement: "users", Identifier: identifiers}
+		err := a.Delete(context.Background(), op, []interface{}{1})
+
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !errors.Is(err, adapter.ErrValidation) {
+			t.Fatalf("expected ValidationError for %d identifiers, got %v", len(identifiers), err)
+		}
+		if verr.Operation != adapter.OpDelete || verr.Table != "users" || len(verr.Fields) != len(identifiers) {
+			t.Errorf("unexpected error %+v", verr)
+		}
+	}
+	if got := script.executed(); len(got) != 0 {
+		t.Errorf("expected nothing to be executed, got %v", got)
+	}
+}
+
+func TestSingleUpdate_RejectsIdentifierOnlyMapping(t *testing.T) {
+	a := NewMySQLAdapter()
+	op := &adapter.Operation{
+		Type:       adapter.OpUpdate,
+		Statement:  "users",
+		Properties: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+	}
+
+	// Validation happens before the statement reaches the database
+	_, err := a.singleUpdate(context.Background(), op, map[string]interface{}{"ID": 1})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+}
+
+func TestMySQLAdapter_UpdateRequiresIdentifier(t *testing.T) {
+	script := &scriptConnector{}
+	a := newScriptAdapter(t, script)
+
+	// A version condition alone would update every row at that version
+	op := &adapter.Operation{
+		Statement:  "users",
+		Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}},
+		Condition:  []adapter.PropertyMapping{{ObjectField: "Version", DataField: "version"}},
+	}
+	err := a.Update(context.Background(), op, []interface{}{map[string]interface{}{"Name": "Ann", "Version": 3}})
+	if !errors.Is(err, adapter.ErrValidation) || !strings.Contains(err.Error(), "no identifier fields") {
+		t.Errorf("expected validation error for update without identifier, got %v", err)
+	}
+
+	op.Identifier = []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}}
+	err = a.Update(context.Background(), op, []interface{}{map[string]interface{}{"Name": "Ann", "Version": 3}})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0] != "ID" {
+		t.Errorf("expected validation error naming ID, got %v", err)
+	}
+
+	if got := script.executed(); len(got) != 0 {
+		t.Errorf("expected no statements to run, got %v", got)
+	}
+}
@@ -0,0 +1,200 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// WarningsConfig configures reading the warnings MySQL reports for writes,
+// such as strings truncated or numbers clamped outside strict sql_mode.
+type WarningsConfig struct {
+	// Capture reads the warnings of every Insert, Update and Execute
+	// statement with SHOW WARNINGS on the statement's connection.
+	Capture bool
+
+	// ErrorCodes are warning codes that fail the write with a
+	// *WarningError, such as 1265 (data truncated) and 1264 (out of range).
+	// Setting them implies Capture and runs each write in a transaction so
+	// a failed write is rolled back.
+	ErrorCodes []int
+}
+
+// enabled reports whether warnings are read.
+func (c WarningsConfig) enabled() bool {
+	return c.Capture || len(c.ErrorCodes) > 0
+}
+
+// isError reports whether a warning code fails the write.
+func (c WarningsConfig) isError(code int) bool {
+	for _, e := range c.ErrorCodes {
+		if e == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Warning is a condition MySQL reported for a statement.
+type Warning struct {
+	// Level is Note, Warning or Error.
+	Level string
+
+	// Code is the MySQL error code, such as 1265.
+	Code int
+
+	// Message describes the condition.
+	Message string
+}
+
+// String returns the warning as MySQL prints it.
+func (w Warning) String() string {
+	return fmt.Sprintf("%s %d: %s", w.Level, w.Code, w.Message)
+}
+
+// WarningError reports a write rolled back because MySQL raised warnings
+// configured as errors. It matches adapter.ErrValidation with errors.Is.
+type WarningError struct {
+	// Warnings holds the warnings that failed the write.
+	Warnings []Warning
+}
+
+// Error implements the error interface.
+func (e *WarningError) Error() string {
+	msgs := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		msgs[i] = w.String()
+	}
+	return "mysql: write raised " + strings.Join(msgs, "; ")
+}
+
+// Unwrap allows errors.Is(err, adapter.ErrValidation) to match.
+func (e *WarningError) Unwrap() error {
+	return adapter.ErrValidation
+}
+
+// WarningCollector gathers the warnings of the writes run with a context
+// returned by WithWarningCollector. It is safe for concurrent use.
+type WarningCollector struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+// Warnings returns the warnings collected so far.
+func (c *WarningCollector) Warnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.warnings...)
+}
+
+// add appends warnings.
+func (c *WarningCollector) add(warnings []Warning) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.warnings = append(c.warnings, warnings...)
+}
+
+// warningCollectorKey is the context key of a WarningCollector.
+type warningCollectorKey struct{}
+
+// WithWarningCollector returns a context whose writes report their warnings
+// to c when warnings are captured.
+func WithWarningCollector(ctx context.Context, c *WarningCollector) context.Context {
+	return context.WithValue(ctx, warningCollectorKey{}, c)
+}
+
+// writeSession is where a write and the statements sharing its session run.
+type writeSession struct {
+	q    queryer
+	conn *sql.Conn
+	tx   *sql.Tx
+}
+
+// openWrite returns the session of a write. Writes that need a transaction
+// (tx) or are failed by warnings run in one, writes whose warnings are
+// captured, whose GTID is tracked or whose session must be shared (pin) on a
+// pinned connection, and other writes on the pool.
+func (a *MySQLAdapter) openWrite(ctx context.Context, pin, tx bool) (*writeSession, error) {
+	if !pin && !tx && !a.config.Warnings.enabled() && !a.tracksWrites(ctx) {
+		return &writeSession{q: a.db}, nil
+	}
+
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: failed to acquire connection: %w", err)
+	}
+	if !tx && len(a.config.Warnings.ErrorCodes) == 0 {
+		return &writeSession{q: conn, conn: conn}, nil
+	}
+	sqlTx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("mysql: failed to begin transaction: %w", err)
+	}
+	return &writeSession{q: sqlTx, conn: conn, tx: sqlTx}, nil
+}
+
+// commitWrite commits the session's transaction, if any, and records the
+// write for read-your-writes consistency.
+func (a *MySQLAdapter) commitWrite(ctx context.Context, w *writeSession) error {
+	if w.tx != nil {
+		if err := w.tx.Commit(); err != nil {
+			return fmt.Errorf("mysql: commit failed: %w", err)
+		}
+	}
+	a.trackWrite(ctx, w.conn)
+	return nil
+}
+
+// close rolls back an uncommitted transaction and releases the connection.
+func (w *writeSession) close() {
+	if w.tx != nil {
+		_ = w.tx.Rollback()
+	}
+	if w.conn != nil {
+		_ = w.conn.Close()
+	}
+}
+
+// warnings reads the warnings of the statement just run in w, reports them
+// to the context's collector, and returns a *WarningError if any of them is
+// configured to fail the write.
+func (a *MySQLAdapter) warnings(ctx context.Context, w *writeSession) ([]Warning, error) {
+	cfg := a.config.Warnings
+	if !cfg.enabled() {
+		return nil, nil
+	}
+
+	rows, err := w.q.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil, fmt.Errorf("mysql: failed to read warnings: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var warnings, failed []Warning
+	for rows.Next() {
+		var wn Warning
+		if err := rows.Scan(&wn.Level, &wn.Code, &wn.Message); err != nil {
+			return nil, fmt.Errorf("mysql: failed to read warnings: %w", err)
+		}
+		warnings = append(warnings, wn)
+		if cfg.isError(wn.Code) {
+			failed = append(failed, wn)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql: failed to read warnings: %w", err)
+	}
+
+	if c, ok := ctx.Value(warningCollectorKey{}).(*WarningCollector); ok && len(warnings) > 0 {
+		c.add(warnings)
+	}
+	if len(failed) > 0 {
+		return warnings, &WarningError{Warnings: failed}
+	}
+	return warnings, nil
+}
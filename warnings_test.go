@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toutaio/toutago-datamapper/adapter"
+)
+
+// truncated is the SHOW WARNINGS result of a truncated string.
+var truncated = fakeResult{
+	columns: []string{"Level", "Code", "Message"},
+	rows:    [][]driver.Value{{"Warning", int64(1265), "Data truncated for column 'name' at row 1"}},
+}
+
+func TestMySQLAdapter_CaptureWarnings(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"UPDATE users SET name = ? WHERE id = ?": {},
+		"UPDATE users SET active = 0":            {},
+		"SHOW WARNINGS":                          truncated,
+	}}
+	a, err := NewMySQLAdapterWithOptions(WithWarnings(WarningsConfig{Capture: true}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+
+	collector := &WarningCollector{}
+	ctx := WithWarningCollector(context.Background(), collector)
+	op := &adapter.Operation{
+		Statement:  "users",
+		Properties: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}, {ObjectField: "Name", DataField: "name"}},
+		Identifier: []adapter.PropertyMapping{{ObjectField: "ID", DataField: "id"}},
+	}
+	res, err := a.UpdateWithResult(ctx, op, []interface{}{map[string]interface{}{"ID": 1, "Name": strings.Repeat("x", 300)}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Code != 1265 || res.Warnings[0].Level != "Warning" {
+		t.Errorf("unexpected warnings %+v", res.Warnings)
+	}
+
+	out, err := a.Execute(ctx, &adapter.Action{Name: "deactivate", Statement: "UPDATE users SET active = 0"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w, _ := out.(map[string]interface{})["warnings"].([]Warning); len(w) != 1 {
+		t.Errorf("expected warnings in the result, got %v", out)
+	}
+
+	if got := collector.Warnings(); len(got) != 2 {
+		t.Errorf("expected both writes' warnings to be collected, got %+v", got)
+	}
+	// Warnings are read right after each write, on its connection
+	want := []string{"UPDATE users SET name = ? WHERE id = ?", "SHOW WARNINGS", "UPDATE users SET active = 0", "SHOW WARNINGS"}
+	if got := script.executed(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected statements %v", got)
+	}
+}
+
+func TestMySQLAdapter_WarningErrors(t *testing.T) {
+	script := &scriptConnector{results: map[string]fakeResult{
+		"INSERT INTO users (name) VALUES (?)": {},
+		"SHOW WARNINGS":                       truncated,
+	}}
+	a, err := NewMySQLAdapterWithOptions(WithWarnings(WarningsConfig{ErrorCodes: []int{1264, 1265}}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.db = sql.OpenDB(script)
+	defer func() { _ = a.db.Close() }()
+
+	op := &adapter.Operation{Statement: "users", Properties: []adapter.PropertyMapping{{ObjectField: "Name", DataField: "name"}}}
+	err = a.Insert(context.Background(), op, []interface{}{map[string]interface{}{"Name": "x"}})
+
+	var werr *WarningError
+	if !errors.As(err, &werr) || len(werr.Warnings) != 1 || werr.Warnings[0].Code != 1265 {
+		t.Fatalf("expected WarningError, got %v", err)
+	}
+	if !errors.Is(err, adapter.ErrValidation) {
+		t.Error("expected error to match adapter.ErrValidation")
+	}
+	got := script.executed()
+	if len(got) != 3 || got[2] != "ROLLBACK" {
+		t.Errorf("expected the insert to be rolled back, got %v", got)
+	}
+
+	// Warnings not configured as errors let the write commit
+	a.config.Warnings.ErrorCodes = []int{1264}
+	if err := a.Insert(context.Background(), op, []interface{}{map[string]interface{}{"Name": "x"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := script.executed(); got[len(got)-1] != "COMMIT" {
+		t.Errorf("expected the insert to commit, got %v", got)
+	}
+}
+
+func TestConfig_Warnings(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.apply(map[string]interface{}{
+		"capture_warnings":    true,
+		"warning_error_codes": []interface{}{1265, float64(1264), "1366"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Warnings.Capture || len(cfg.Warnings.ErrorCodes) != 3 || !cfg.Warnings.isError(1366) {
+		t.Errorf("unexpected warnings config %+v", cfg.Warnings)
+	}
+	if err := cfg.apply(map[string]interface{}{"warning_error_codes": []interface{}{"data truncated"}}); err == nil {
+		t.Error("expected error for non-numeric code")
+	}
+}